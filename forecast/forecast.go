@@ -0,0 +1,184 @@
+// Package forecast projects the daily balances of a user's accounts forward in time.
+package forecast
+
+import (
+	"time"
+
+	"github.com/groggygopher/oyster/recurring"
+	"github.com/groggygopher/oyster/register"
+)
+
+// Point is the projected balance of an Account at the end of a day.
+type Point struct {
+	Date    *time.Time `json:"date"`
+	Balance float64    `json:"balance"`
+}
+
+// Crossing is a day on which a projected balance moves across its Account's threshold. Below is
+// true when the balance drops under the threshold, and false when it recovers.
+type Crossing struct {
+	Date    *time.Time `json:"date"`
+	Balance float64    `json:"balance"`
+	Below   bool       `json:"below"`
+}
+
+// Projection is the projected daily balance series of a single Account.
+type Projection struct {
+	Account   string      `json:"account"`
+	Threshold float64     `json:"threshold"`
+	Start     float64     `json:"start"`
+	Balances  []*Point    `json:"balances"`
+	Crossings []*Crossing `json:"crossings"`
+}
+
+// Options controls a forecast.
+type Options struct {
+	// From is the first projected day.
+	From time.Time
+	// Days is how many days to project.
+	Days int
+	// DefaultAccount is charged with upcoming transactions that name no account, and with the
+	// unspent remainder of every Budget. The first account is used if it is empty.
+	DefaultAccount string
+}
+
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func dayKey(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// spent totals the Category amounts of the given Transactions against the named Category, or any of
+// its sub-categories, in the given month.
+func spent(trans []*register.Transaction, name string, month time.Time) float64 {
+	var total float64
+	for _, t := range trans {
+		if t.Date == nil || t.IsTransfer() || t.Date.Year() != month.Year() || t.Date.Month() != month.Month() {
+			continue
+		}
+		for _, c := range t.Category {
+			if _, ok := register.MoveCategory(c.Name, name, name); ok {
+				total += c.Amount
+			}
+		}
+	}
+	return total
+}
+
+// scheduled totals the amounts of the given Occurrences in the named Category, or any of its
+// sub-categories, on the days from start up to end.
+func scheduled(upcoming []*recurring.Occurrence, name string, start, end time.Time) float64 {
+	var total float64
+	for _, o := range upcoming {
+		if o.Category == "" || o.Date.Before(start) || !o.Date.Before(end) {
+			continue
+		}
+		if _, ok := register.MoveCategory(o.Category, name, name); ok {
+			total += o.Amount
+		}
+	}
+	return total
+}
+
+// budgetDaily returns, for every projected day, the share of the Budgets left unspent in that day's
+// month. Budget amounts are limits on spending, so the shares are negative. Upcoming transactions
+// in a budgeted category are already projected on their own days, so they count as spent. A Budget
+// for a sub-category is left out in months when one of its parents has a Budget, since the
+// parent's limit covers the sub-category's spending too.
+func budgetDaily(trans []*register.Transaction, upcoming []*recurring.Occurrence, budgets []*register.Budget, from time.Time, days int) map[string]float64 {
+	daily := make(map[string]float64)
+	until := from.AddDate(0, 0, days)
+	for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location()); month.Before(until); month = month.AddDate(0, 1, 0) {
+		start := month
+		if start.Before(from) {
+			start = from
+		}
+		end := month.AddDate(0, 1, 0)
+		left := int(end.Sub(start).Hours()/24 + 0.5)
+		amounts := make(map[string]float64)
+		for _, b := range budgets {
+			if amt, ok := b.Amount(month.Month(), month.Year()); ok {
+				amounts[b.Name()] = amt
+			}
+		}
+		for name, amt := range amounts {
+			if budgetedParent(amounts, name) {
+				continue
+			}
+			remaining := amt + spent(trans, name, month) + scheduled(upcoming, name, start, end)
+			if remaining <= 0 {
+				continue
+			}
+			for d := start; d.Before(end) && d.Before(until); d = d.AddDate(0, 0, 1) {
+				daily[dayKey(d)] -= remaining / float64(left)
+			}
+		}
+	}
+	return daily
+}
+
+// budgetedParent returns true if any parent of the named Category has an amount in amounts.
+func budgetedParent(amounts map[string]float64, name string) bool {
+	for other := range amounts {
+		if _, ok := register.MoveCategory(name, other, other); ok && other != name {
+			return true
+		}
+	}
+	return false
+}
+
+// Project starts from the current balance of every Account and projects daily balances forward
+// using the given upcoming transactions and the unspent part of every Budget.
+func Project(accounts []*register.Account, trans []*register.Transaction, upcoming []*recurring.Occurrence, budgets []*register.Budget, opts *Options) []*Projection {
+	if len(accounts) == 0 {
+		return nil
+	}
+	from := dayOf(opts.From)
+	def := opts.DefaultAccount
+	if def == "" {
+		def = accounts[0].Name
+	}
+
+	changes := make(map[string]map[string]float64)
+	for _, a := range accounts {
+		changes[a.Name] = make(map[string]float64)
+	}
+	for _, o := range upcoming {
+		account := o.Account
+		if _, ok := changes[account]; !ok {
+			account = def
+		}
+		if c, ok := changes[account]; ok {
+			c[dayKey(*o.Date)] += o.Amount
+		}
+	}
+	if c, ok := changes[def]; ok {
+		for d, amt := range budgetDaily(trans, upcoming, budgets, from, opts.Days) {
+			c[d] += amt
+		}
+	}
+
+	var res []*Projection
+	for _, a := range accounts {
+		p := &Projection{
+			Account:   a.Name,
+			Threshold: a.Threshold,
+			Start:     a.CurrentBalance(trans),
+		}
+		bal := p.Start
+		below := bal < a.Threshold
+		for i := 0; i < opts.Days; i++ {
+			d := from.AddDate(0, 0, i)
+			bal += changes[a.Name][dayKey(d)]
+			p.Balances = append(p.Balances, &Point{Date: &d, Balance: bal})
+			if now := bal < a.Threshold; now != below {
+				below = now
+				p.Crossings = append(p.Crossings, &Crossing{Date: &d, Balance: bal, Below: below})
+			}
+		}
+		res = append(res, p)
+	}
+	return res
+}
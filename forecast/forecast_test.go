@@ -0,0 +1,124 @@
+package forecast
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/groggygopher/oyster/recurring"
+	"github.com/groggygopher/oyster/register"
+)
+
+func day(y int, m time.Month, d int) *time.Time {
+	t := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestProject(t *testing.T) {
+	accounts := []*register.Account{
+		{Name: "checking", Balance: 500, AsOf: day(2018, time.June, 1), Threshold: 0},
+		{Name: "savings", Balance: 1000, AsOf: day(2018, time.June, 1), Threshold: 100},
+	}
+	trans := []*register.Transaction{
+		// After the balance date, so it counts.
+		{Account: "checking", Amount: -50, Date: day(2018, time.June, 5)},
+		// Before the balance date, so it is already in the balance.
+		{Account: "checking", Amount: -1000, Date: day(2018, time.May, 30)},
+	}
+	upcoming := []*recurring.Occurrence{
+		{Description: "rent", Amount: -600, Date: day(2018, time.June, 12)},
+		{Description: "payday", Account: "checking", Amount: 800, Date: day(2018, time.June, 15)},
+		{Description: "car", Account: "savings", Amount: -950, Date: day(2018, time.June, 11)},
+	}
+
+	ps := Project(accounts, trans, upcoming, nil, &Options{From: *day(2018, time.June, 10), Days: 10})
+	if got, want := len(ps), 2; got != want {
+		t.Fatalf("projections: got: %d, want: %d", got, want)
+	}
+	checking, savings := ps[0], ps[1]
+	if got, want := checking.Start, 450.0; got != want {
+		t.Errorf("checking start: got: %f, want: %f", got, want)
+	}
+	if got, want := len(checking.Balances), 10; got != want {
+		t.Fatalf("checking balances: got: %d, want: %d", got, want)
+	}
+	if got, want := checking.Balances[9].Balance, 650.0; got != want {
+		t.Errorf("checking end balance: got: %f, want: %f", got, want)
+	}
+	if got, want := len(checking.Crossings), 2; got != want {
+		t.Fatalf("checking crossings: got: %d, want: %d", got, want)
+	}
+	if c := checking.Crossings[0]; !c.Below || !c.Date.Equal(*day(2018, time.June, 12)) {
+		t.Errorf("checking first crossing: got: %v below %t, want: 2018-06-12 below true", c.Date, c.Below)
+	}
+	if c := checking.Crossings[1]; c.Below || !c.Date.Equal(*day(2018, time.June, 15)) {
+		t.Errorf("checking second crossing: got: %v below %t, want: 2018-06-15 below false", c.Date, c.Below)
+	}
+	if got, want := len(savings.Crossings), 1; got != want {
+		t.Errorf("savings crossings: got: %d, want: %d", got, want)
+	}
+}
+
+func TestProjectBudgets(t *testing.T) {
+	accounts := []*register.Account{
+		{Name: "checking", Balance: 1000, AsOf: day(2018, time.June, 1)},
+	}
+	trans := []*register.Transaction{
+		{Date: day(2018, time.June, 3), Category: []*register.Category{{Name: "Food:Groceries", Amount: -100}}},
+	}
+	food := register.NewBudget("Food")
+	food.SetAmount(time.June, 2018, 400)
+
+	// 300 is left in the Food budget, spread over the last 10 days of June.
+	ps := Project(accounts, trans, nil, []*register.Budget{food}, &Options{From: *day(2018, time.June, 21), Days: 10})
+	if got, want := ps[0].Balances[9].Balance, 700.0; math.Abs(got-want) > 0.001 {
+		t.Errorf("end balance: got: %f, want: %f", got, want)
+	}
+	if got, want := ps[0].Balances[0].Balance, 970.0; math.Abs(got-want) > 0.001 {
+		t.Errorf("first balance: got: %f, want: %f", got, want)
+	}
+}
+
+func TestProjectNestedBudgets(t *testing.T) {
+	accounts := []*register.Account{
+		{Name: "checking", Balance: 1000, AsOf: day(2018, time.June, 1)},
+	}
+	trans := []*register.Transaction{
+		{Date: day(2018, time.June, 3), Category: []*register.Category{{Name: "Food:Groceries", Amount: -100}}},
+	}
+	food := register.NewBudget("Food")
+	food.SetAmount(time.June, 2018, 400)
+	groceries := register.NewBudget("Food:Groceries")
+	groceries.SetAmount(time.June, 2018, 250)
+	groceries.SetAmount(time.July, 2018, 250)
+
+	// The Food budget covers groceries in June, so only its 300 left is spread over June. In July
+	// only the Groceries budget is set.
+	ps := Project(accounts, trans, nil, []*register.Budget{food, groceries}, &Options{From: *day(2018, time.June, 21), Days: 11})
+	if got, want := ps[0].Balances[9].Balance, 700.0; math.Abs(got-want) > 0.001 {
+		t.Errorf("June balance: got: %f, want: %f", got, want)
+	}
+	if got, want := ps[0].Balances[10].Balance, 700-250.0/31; math.Abs(got-want) > 0.001 {
+		t.Errorf("July balance: got: %f, want: %f", got, want)
+	}
+}
+
+func TestProjectBudgetedSchedule(t *testing.T) {
+	accounts := []*register.Account{
+		{Name: "checking", Balance: 2000, AsOf: day(2018, time.June, 1)},
+	}
+	housing := register.NewBudget("Housing")
+	housing.SetAmount(time.June, 2018, 1000)
+	upcoming := []*recurring.Occurrence{
+		{Description: "rent", Category: "Housing:Rent", Amount: -800, Date: day(2018, time.June, 25)},
+	}
+
+	// The rent is projected on its own day, so only the 200 left over is spread across the month.
+	ps := Project(accounts, nil, upcoming, []*register.Budget{housing}, &Options{From: *day(2018, time.June, 21), Days: 10})
+	if got, want := ps[0].Balances[9].Balance, 1000.0; math.Abs(got-want) > 0.001 {
+		t.Errorf("end balance: got: %f, want: %f", got, want)
+	}
+	if got, want := ps[0].Balances[0].Balance, 1980.0; math.Abs(got-want) > 0.001 {
+		t.Errorf("first balance: got: %f, want: %f", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/groggygopher/oyster/register"
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewAccountHandler returns a new AccountHandler with the given SessionManager.
+func NewAccountHandler(man *session.Manager) *AccountHandler {
+	return &AccountHandler{manager: man}
+}
+
+// AccountHandler manages the balances and thresholds of a user's accounts.
+type AccountHandler struct {
+	manager *session.Manager
+}
+
+func (ah *AccountHandler) get(w http.ResponseWriter, usr *session.User) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(usr.Accounts()); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+func (ah *AccountHandler) modify(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	a := &register.Account{}
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(a); err != nil {
+		http.Error(w, "invalid JSON Account object", http.StatusBadRequest)
+		log.Printf("error: decode account: %v", err)
+		return
+	}
+	if req.Method == http.MethodDelete {
+		if !usr.DeleteAccount(a.Name) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf("No account with name '%s' exists", a.Name)))
+			return
+		}
+	} else if err := usr.SetAccount(a); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeHTTP lists accounts on GET, adds or replaces an account on PUT and removes one on DELETE.
+func (ah *AccountHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(ah.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		ah.get(w, usr)
+	case http.MethodPut, http.MethodDelete:
+		ah.modify(w, req, usr)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method: %s", req.Method)))
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewAttachmentHandler returns a new AttachmentHandler with the given SessionManager.
+func NewAttachmentHandler(man *session.Manager) *AttachmentHandler {
+	return &AttachmentHandler{manager: man}
+}
+
+// AttachmentHandler uploads, downloads and deletes files attached to a user's transactions.
+type AttachmentHandler struct {
+	manager *session.Manager
+}
+
+// writeLookupError answers a request that failed with err: 404 for a missing object, 400 for a
+// request that cannot be carried out as it was made, and 500 for anything else, which is logged.
+func writeLookupError(w http.ResponseWriter, err error) {
+	if _, ok := err.(*session.InputError); ok {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	switch err {
+	case session.ErrNoTransaction, session.ErrNoAttachment, session.ErrNoImport, session.ErrNoSession, session.ErrNoSnapshot:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(err.Error()))
+	default:
+		log.Printf("error: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("An internal error occurred"))
+	}
+}
+
+func (ah *AttachmentHandler) get(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	q := req.URL.Query()
+	att, data, err := ah.manager.Attachment(usr, q.Get("transaction"), q.Get("id"))
+	if err != nil {
+		writeLookupError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", att.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.Name}))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func (ah *AttachmentHandler) post(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	data, err := ioutil.ReadAll(io.LimitReader(req.Body, session.MaxAttachmentSize+1))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("error reading attachment"))
+		log.Printf("error: ioutil.ReadAll: %v", err)
+		return
+	}
+	q := req.URL.Query()
+	att, err := ah.manager.AddAttachment(usr, q.Get("transaction"), q.Get("name"), req.Header.Get("Content-Type"), data)
+	if err != nil {
+		writeLookupError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(att); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+func (ah *AttachmentHandler) delete(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	q := req.URL.Query()
+	if err := ah.manager.DeleteAttachment(usr, q.Get("transaction"), q.Get("id")); err != nil {
+		writeLookupError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeHTTP downloads an attachment on GET, uploads a new attachment on POST and deletes an
+// attachment on DELETE. The transaction, id and name query parameters select the attachment.
+func (ah *AttachmentHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(ah.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		ah.get(w, req, usr)
+	case http.MethodPost:
+		ah.post(w, req, usr)
+	case http.MethodDelete:
+		ah.delete(w, req, usr)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method: %s", req.Method)))
+	}
+}
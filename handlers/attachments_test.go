@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/groggygopher/oyster/register"
+	"github.com/groggygopher/oyster/session"
+	"golang.org/x/net/publicsuffix"
+)
+
+func TestAttachments(t *testing.T) {
+	m, err := session.CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/attachments", NewAttachmentHandler(m))
+	mux.Handle("/notes", NewNotesHandler(m))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := srv.Client()
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		t.Fatalf("cookiejar.New(): %v", err)
+	}
+	client.Jar = jar
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("url.Parse(%s): %v", srv.URL, err)
+	}
+
+	// Login.
+	usr, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("testManager.Login: %v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{&http.Cookie{Name: sessCookieKey, Value: token}})
+	usr.ImportTransactions([]*register.Transaction{{ID: "trans"}})
+
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/notes", bytes.NewReader([]byte(`{"id":"trans","notes":"warranty"}`)))
+	if err != nil {
+		t.Fatalf("http.NewRequest: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("client.Do: %v", err)
+	}
+	if got, want := resp.StatusCode, http.StatusNoContent; got != want {
+		t.Errorf("PUT /notes: got: %d, want: %d", got, want)
+	}
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+	urlStr := fmt.Sprintf("%s/attachments?transaction=trans&name=receipt.png", srv.URL)
+	resp, err = client.Post(urlStr, "text/plain", bytes.NewReader([]byte("hello")))
+	if err != nil {
+		t.Fatalf("client.Post(%s): %v", urlStr, err)
+	}
+	if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+		t.Errorf("bad content type: POST /attachments: got: %d, want: %d", got, want)
+	}
+	resp, err = client.Post(urlStr, "image/png", bytes.NewReader(png))
+	if err != nil {
+		t.Fatalf("client.Post(%s): %v", urlStr, err)
+	}
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("POST /attachments: got: %d, want: %d", got, want)
+	}
+
+	att := usr.Transactions()[0].Attachments[0]
+	urlStr = fmt.Sprintf("%s/attachments?transaction=trans&id=%s", srv.URL, att.ID)
+	resp, err = client.Get(urlStr)
+	if err != nil {
+		t.Fatalf("client.Get(%s): %v", urlStr, err)
+	}
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("GET /attachments: got: %d, want: %d", got, want)
+	}
+	if got, want := resp.Header.Get("Content-Type"), "image/png"; got != want {
+		t.Errorf("GET /attachments content type: got: %s, want: %s", got, want)
+	}
+
+	req, err = http.NewRequest(http.MethodDelete, urlStr, nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest: %v", err)
+	}
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("client.Do: %v", err)
+	}
+	if got, want := resp.StatusCode, http.StatusNoContent; got != want {
+		t.Errorf("DELETE /attachments: got: %d, want: %d", got, want)
+	}
+	resp, err = client.Get(urlStr)
+	if err != nil {
+		t.Fatalf("client.Get(%s): %v", urlStr, err)
+	}
+	if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+		t.Errorf("after delete: GET /attachments: got: %d, want: %d", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewBudgetHandler returns a new BudgetHandler with the given SessionManager.
+func NewBudgetHandler(man *session.Manager) *BudgetHandler {
+	return &BudgetHandler{manager: man}
+}
+
+// BudgetHandler manages the monthly budgets of a user's categories.
+type BudgetHandler struct {
+	manager *session.Manager
+}
+
+func (bh *BudgetHandler) get(w http.ResponseWriter, usr *session.User) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(usr.Budgets()); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+func (bh *BudgetHandler) put(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	body := &struct {
+		Category string  `json:"category"`
+		Month    int     `json:"month"`
+		Year     int     `json:"year"`
+		Amount   float64 `json:"amount"`
+	}{}
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(body); err != nil {
+		http.Error(w, "invalid budget request JSON body", http.StatusBadRequest)
+		log.Printf("error: decode budget request body: %v", err)
+		return
+	}
+	if err := usr.SetBudget(body.Category, time.Month(body.Month), body.Year, body.Amount); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeHTTP lists budgets on GET and sets a category's budget for a month on PUT.
+func (bh *BudgetHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(bh.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		bh.get(w, usr)
+	case http.MethodPut:
+		bh.put(w, req, usr)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method: %s", req.Method)))
+	}
+}
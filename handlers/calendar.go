@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/groggygopher/oyster/recurring"
+	"github.com/groggygopher/oyster/session"
+)
+
+const defaultCalendarDays = 90
+
+// NewCalendarHandler returns a new CalendarHandler with the given SessionManager.
+func NewCalendarHandler(man *session.Manager) *CalendarHandler {
+	return &CalendarHandler{manager: man}
+}
+
+// CalendarHandler serves a user's upcoming transactions as an iCalendar feed. Calendar clients
+// reach the feed with a secret token instead of a session cookie.
+type CalendarHandler struct {
+	manager *session.Manager
+}
+
+func (ch *CalendarHandler) feed(w http.ResponseWriter, req *http.Request) {
+	days, err := queryDays(req, defaultCalendarDays)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	now := time.Now()
+	name, occs, err := ch.manager.Calendar(req.URL.Query().Get("token"), now, days)
+	if err == session.ErrNoCalendar {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("error: manager.Calendar: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("An internal error occurred"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := recurring.WriteICS(w, fmt.Sprintf("Oyster bills for %s", name), occs, now); err != nil {
+		log.Printf("error: recurring.WriteICS: %v", err)
+	}
+}
+
+func (ch *CalendarHandler) rotate(w http.ResponseWriter, req *http.Request) {
+	usr := RequestUser(ch.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	token, err := ch.manager.RotateCalendarToken(usr)
+	if err != nil {
+		log.Printf("error: manager.RotateCalendarToken: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("An internal error occurred"))
+		return
+	}
+	resp := &struct {
+		Token string `json:"token"`
+		URL   string `json:"url"`
+	}{
+		Token: token,
+		URL:   fmt.Sprintf("%s?token=%s", req.URL.Path, token),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(resp); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+func (ch *CalendarHandler) disable(w http.ResponseWriter, req *http.Request) {
+	usr := RequestUser(ch.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if err := ch.manager.DisableCalendar(usr); err != nil {
+		log.Printf("error: manager.DisableCalendar: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("An internal error occurred"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeHTTP serves the iCalendar feed of the token query parameter on GET. With a session, PUT
+// issues a new token, revoking the old one, and DELETE turns the feed off.
+func (ch *CalendarHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	switch req.Method {
+	case http.MethodGet:
+		ch.feed(w, req)
+	case http.MethodPut:
+		ch.rotate(w, req)
+	case http.MethodDelete:
+		ch.disable(w, req)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method: %s", req.Method)))
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/groggygopher/oyster/session"
+	"golang.org/x/net/publicsuffix"
+)
+
+func TestCalendar(t *testing.T) {
+	m, err := session.CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/calendar.ics", NewCalendarHandler(m))
+	mux.Handle("/schedules", NewScheduleHandler(m))
+	mux.Handle("/upcoming", NewUpcomingHandler(m))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := srv.Client()
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		t.Fatalf("cookiejar.New(): %v", err)
+	}
+	client.Jar = jar
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("url.Parse(%s): %v", srv.URL, err)
+	}
+
+	// Login.
+	_, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("testManager.Login: %v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{&http.Cookie{Name: sessCookieKey, Value: token}})
+
+	start := time.Now().Format(time.RFC3339)
+	resp, err := client.Post(srv.URL+"/schedules", "application/json", bytes.NewReader([]byte(fmt.Sprintf(`{"description":"rent","amount":-1000,"cadence":"monthly","start":%q}`, start))))
+	if err != nil {
+		t.Fatalf("client.Post: %v", err)
+	}
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("POST /schedules: got: %d, want: %d", got, want)
+	}
+	resp, err = client.Post(srv.URL+"/schedules", "application/json", bytes.NewReader([]byte(`{"description":"rent","cadence":"daily"}`)))
+	if err != nil {
+		t.Fatalf("client.Post: %v", err)
+	}
+	if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+		t.Errorf("bad schedule: POST /schedules: got: %d, want: %d", got, want)
+	}
+
+	resp, err = client.Get(srv.URL + "/upcoming?days=10")
+	if err != nil {
+		t.Fatalf("client.Get: %v", err)
+	}
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Errorf("GET /upcoming: got: %d, want: %d", got, want)
+	}
+	resp, err = client.Get(srv.URL + "/upcoming?days=731")
+	if err != nil {
+		t.Fatalf("client.Get: %v", err)
+	}
+	if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+		t.Errorf("too many days: GET /upcoming: got: %d, want: %d", got, want)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/calendar.ics", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest: %v", err)
+	}
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("client.Do: %v", err)
+	}
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("PUT /calendar.ics: got: %d, want: %d", got, want)
+	}
+	feed := &struct {
+		URL string `json:"url"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(feed); err != nil {
+		t.Fatalf("json.Decode: %v", err)
+	}
+
+	// Calendar clients have no session cookie.
+	anon := &http.Client{}
+	resp, err = anon.Get(srv.URL + feed.URL)
+	if err != nil {
+		t.Fatalf("client.Get: %v", err)
+	}
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Errorf("GET %s: got: %d, want: %d", feed.URL, got, want)
+	}
+	if got, want := resp.Header.Get("Content-Type"), "text/calendar; charset=utf-8"; got != want {
+		t.Errorf("content type: got: %s, want: %s", got, want)
+	}
+	resp, err = anon.Get(srv.URL + "/calendar.ics?token=bad")
+	if err != nil {
+		t.Fatalf("client.Get: %v", err)
+	}
+	if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+		t.Errorf("bad token: GET /calendar.ics: got: %d, want: %d", got, want)
+	}
+}
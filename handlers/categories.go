@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewCategoryHandler returns a new CategoryHandler with the given SessionManager.
+func NewCategoryHandler(man *session.Manager) *CategoryHandler {
+	return &CategoryHandler{manager: man}
+}
+
+// CategoryHandler manages a user's category tree.
+type CategoryHandler struct {
+	manager *session.Manager
+}
+
+type categoryRequest struct {
+	Name  string `json:"name"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Merge bool   `json:"merge"`
+}
+
+func readCategoryRequest(r io.Reader) (*categoryRequest, error) {
+	cr := &categoryRequest{}
+	dec := json.NewDecoder(r)
+	if err := dec.Decode(cr); err != nil {
+		return nil, fmt.Errorf("json.Decode: %v", err)
+	}
+	return cr, nil
+}
+
+func (ch *CategoryHandler) get(w http.ResponseWriter, usr *session.User) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(usr.CategoryTotals()); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+func (ch *CategoryHandler) modify(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	cr, err := readCategoryRequest(req.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid JSON category request"))
+		log.Printf("error: readCategoryRequest: %v", err)
+		return
+	}
+	switch req.Method {
+	case http.MethodPost:
+		err = usr.AddCategory(cr.Name)
+	case http.MethodPut:
+		if cr.Merge {
+			err = usr.MergeCategory(cr.From, cr.To)
+		} else {
+			err = usr.RenameCategory(cr.From, cr.To)
+		}
+	case http.MethodDelete:
+		err = usr.RemoveCategory(cr.Name)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeHTTP serves the category roll-ups on GET, adds a category on POST, renames or merges a
+// category on PUT and removes an unused category on DELETE.
+func (ch *CategoryHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(ch.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		ch.get(w, usr)
+	case http.MethodPost, http.MethodPut, http.MethodDelete:
+		ch.modify(w, req, usr)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method: %s", req.Method)))
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/groggygopher/oyster/session"
+	"golang.org/x/net/publicsuffix"
+)
+
+func TestCategories(t *testing.T) {
+	m, err := session.CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/categories", NewCategoryHandler(m))
+	mux.Handle("/rules", NewRuleHandler(m))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := srv.Client()
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		t.Fatalf("cookiejar.New(): %v", err)
+	}
+	client.Jar = jar
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("url.Parse(%s): %v", srv.URL, err)
+	}
+
+	// Login.
+	_, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("testManager.Login: %v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{&http.Cookie{Name: sessCookieKey, Value: token}})
+
+	tests := []struct {
+		method   string
+		path     string
+		body     string
+		wantCode int
+	}{
+		// Order matters!
+		{
+			method:   http.MethodGet,
+			path:     "/categories",
+			wantCode: http.StatusOK,
+		},
+		{
+			method:   http.MethodPost,
+			path:     "/rules",
+			body:     `{"name":"costco","category":"Food:Grocery"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			method:   http.MethodPost,
+			path:     "/categories",
+			body:     `{"name":"Food:Grocery"}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			method:   http.MethodPost,
+			path:     "/rules",
+			body:     `{"name":"costco","category":"Food:Grocery"}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			method:   http.MethodPut,
+			path:     "/categories",
+			body:     `{"from":"Food:Grocery","to":"Food:Groceries"}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			method:   http.MethodPut,
+			path:     "/categories",
+			body:     `{"from":"Food:Groceries","to":"Travel","merge":true}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			method:   http.MethodDelete,
+			path:     "/categories",
+			body:     `{"name":"Food:Groceries"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			method:   http.MethodDelete,
+			path:     "/rules",
+			body:     `{"name":"costco"}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			method:   http.MethodDelete,
+			path:     "/categories",
+			body:     `{"name":"Food:Groceries"}`,
+			wantCode: http.StatusNoContent,
+		},
+	}
+
+	for _, test := range tests {
+		urlStr := fmt.Sprintf("%s%s", srv.URL, test.path)
+		req, err := http.NewRequest(test.method, urlStr, bytes.NewReader([]byte(test.body)))
+		if err != nil {
+			t.Fatalf("http.NewRequest: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("client.Do(%s %s): %v", test.method, urlStr, err)
+		}
+		if got, want := resp.StatusCode, test.wantCode; got != want {
+			t.Errorf("%s %s %s: got: %d, want: %d", test.method, test.path, test.body, got, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/groggygopher/oyster/register"
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewDuplicateHandler returns a new DuplicateHandler with the given SessionManager.
+func NewDuplicateHandler(man *session.Manager) *DuplicateHandler {
+	return &DuplicateHandler{manager: man}
+}
+
+// DuplicateHandler serves a user's suspected duplicate transactions for review.
+type DuplicateHandler struct {
+	manager *session.Manager
+}
+
+func (dh *DuplicateHandler) get(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	days, err := queryDays(req, register.DefaultDuplicateDays)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(usr.Duplicates(days)); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+func (dh *DuplicateHandler) modify(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	body := &struct {
+		Keep string `json:"keep"`
+		Drop string `json:"drop"`
+		A    string `json:"a"`
+		B    string `json:"b"`
+	}{}
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(body); err != nil {
+		http.Error(w, "invalid duplicate request JSON body", http.StatusBadRequest)
+		log.Printf("error: decode duplicate request body: %v", err)
+		return
+	}
+
+	var err error
+	if req.Method == http.MethodPost {
+		err = usr.MergeDuplicate(body.Keep, body.Drop)
+	} else {
+		err = usr.DismissDuplicate(body.A, body.B)
+	}
+	if err != nil {
+		writeLookupError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeHTTP serves suspected duplicates on GET, merges the drop transaction of a pair into the
+// keep transaction on POST and dismisses a pair on DELETE. The days query parameter bounds how far
+// apart the copies may be.
+func (dh *DuplicateHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(dh.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		dh.get(w, req, usr)
+	case http.MethodPost, http.MethodDelete:
+		dh.modify(w, req, usr)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method: %s", req.Method)))
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/groggygopher/oyster/register"
+	"github.com/groggygopher/oyster/session"
+	"golang.org/x/net/publicsuffix"
+)
+
+func TestDuplicates(t *testing.T) {
+	m, err := session.CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+
+	duplicateHdl := NewDuplicateHandler(m)
+	srv := httptest.NewServer(duplicateHdl)
+	defer srv.Close()
+
+	client := srv.Client()
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		t.Fatalf("cookiejar.New(): %v", err)
+	}
+	client.Jar = jar
+	urlStr := fmt.Sprintf("%s/duplicates", srv.URL)
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		t.Fatalf("url.Parse(%s): %v", urlStr, err)
+	}
+
+	// Login.
+	usr, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("testManager.Login: %v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{&http.Cookie{Name: sessCookieKey, Value: token}})
+	now := time.Now()
+	usr.ImportTransactions([]*register.Transaction{
+		{ID: "a", Description: "COFFEE", Amount: -5, Date: &now},
+		{ID: "b", Description: "COFFEE", Amount: -5, Date: &now},
+		{ID: "c", Description: "COFFEE", Amount: -5, Date: &now},
+	})
+
+	tests := []struct {
+		method   string
+		query    string
+		body     string
+		wantCode int
+	}{
+		// Order matters!
+		{
+			method:   http.MethodGet,
+			wantCode: http.StatusOK,
+		},
+		{
+			method:   http.MethodGet,
+			query:    "?days=-1",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			method:   http.MethodDelete,
+			body:     `{"a":"a","b":"missing"}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			method:   http.MethodDelete,
+			body:     `{"a":"a","b":"b"}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			method:   http.MethodPost,
+			body:     `{"keep":"a","drop":"a"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			method:   http.MethodPost,
+			body:     `{"keep":"a","drop":"c"}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			method:   http.MethodPost,
+			body:     `{"keep":"a","drop":"c"}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			method:   http.MethodPut,
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, urlStr+test.query, bytes.NewReader([]byte(test.body)))
+		if err != nil {
+			t.Fatalf("http.NewRequest: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("client.Do(%s): %v", urlStr, err)
+		}
+		if got, want := resp.StatusCode, test.wantCode; got != want {
+			t.Errorf("%s %s %s: got: %d, want: %d", test.method, test.query, test.body, got, want)
+		}
+	}
+}
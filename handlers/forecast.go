@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/groggygopher/oyster/session"
+)
+
+const defaultForecastDays = 60
+
+// NewForecastHandler returns a new ForecastHandler with the given SessionManager.
+func NewForecastHandler(man *session.Manager) *ForecastHandler {
+	return &ForecastHandler{manager: man}
+}
+
+// ForecastHandler serves the projected daily balances of a user's accounts.
+type ForecastHandler struct {
+	manager *session.Manager
+}
+
+// ServeHTTP serves GET queries for the projected balances over the next days query parameter
+// days. The account query parameter names the account charged with unassigned bills and budgets.
+func (fh *ForecastHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(fh.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method %s", req.Method)))
+		return
+	}
+
+	days, err := queryDays(req, defaultForecastDays)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(usr.Forecast(time.Now(), days, req.URL.Query().Get("account"))); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
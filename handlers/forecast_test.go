@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/groggygopher/oyster/session"
+	"golang.org/x/net/publicsuffix"
+)
+
+func TestForecast(t *testing.T) {
+	m, err := session.CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/accounts", NewAccountHandler(m))
+	mux.Handle("/budgets", NewBudgetHandler(m))
+	mux.Handle("/forecast", NewForecastHandler(m))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := srv.Client()
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		t.Fatalf("cookiejar.New(): %v", err)
+	}
+	client.Jar = jar
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("url.Parse(%s): %v", srv.URL, err)
+	}
+
+	// Login.
+	usr, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("testManager.Login: %v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{&http.Cookie{Name: sessCookieKey, Value: token}})
+	if err := usr.AddCategory("Food"); err != nil {
+		t.Fatalf("AddCategory: %v", err)
+	}
+
+	tests := []struct {
+		method   string
+		path     string
+		body     string
+		wantCode int
+	}{
+		// Order matters!
+		{
+			method:   http.MethodPut,
+			path:     "/accounts",
+			body:     `{"name":"checking","balance":100}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			method:   http.MethodPut,
+			path:     "/accounts",
+			body:     `{"name":"checking","balance":100,"asOf":"2018-06-01T00:00:00Z"}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/accounts",
+			wantCode: http.StatusOK,
+		},
+		{
+			method:   http.MethodPut,
+			path:     "/budgets",
+			body:     `{"category":"Travel","month":6,"year":2018,"amount":100}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			method:   http.MethodPut,
+			path:     "/budgets",
+			body:     `{"category":"Food","month":6,"year":2018,"amount":100}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/forecast?days=10",
+			wantCode: http.StatusOK,
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/forecast?days=ten",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			method:   http.MethodDelete,
+			path:     "/accounts",
+			body:     `{"name":"checking"}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			method:   http.MethodDelete,
+			path:     "/accounts",
+			body:     `{"name":"checking"}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		urlStr := fmt.Sprintf("%s%s", srv.URL, test.path)
+		req, err := http.NewRequest(test.method, urlStr, bytes.NewReader([]byte(test.body)))
+		if err != nil {
+			t.Fatalf("http.NewRequest: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("client.Do(%s %s): %v", test.method, urlStr, err)
+		}
+		if got, want := resp.StatusCode, test.wantCode; got != want {
+			t.Errorf("%s %s %s: got: %d, want: %d", test.method, test.path, test.body, got, want)
+		}
+	}
+}
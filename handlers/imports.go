@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewImportHandler returns a new ImportHandler with the given SessionManager.
+func NewImportHandler(man *session.Manager) *ImportHandler {
+	return &ImportHandler{manager: man}
+}
+
+// ImportHandler lists a user's import batches and rolls them back.
+type ImportHandler struct {
+	manager *session.Manager
+}
+
+func (ih *ImportHandler) get(w http.ResponseWriter, usr *session.User) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(usr.ImportBatches()); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+func (ih *ImportHandler) delete(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	body := &struct {
+		ID string `json:"id"`
+	}{}
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(body); err != nil {
+		http.Error(w, "invalid import request JSON body", http.StatusBadRequest)
+		log.Printf("error: decode import request body: %v", err)
+		return
+	}
+	removed, err := ih.manager.RollbackImport(usr, body.ID)
+	if err != nil {
+		log.Printf("error: RollbackImport(%s): %v", body.ID, err)
+		writeLookupError(w, err)
+		return
+	}
+
+	resp := &struct {
+		Removed int `json:"removed"`
+	}{
+		Removed: removed,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(resp); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+// ServeHTTP lists import batches on GET and rolls back an entire batch on DELETE.
+func (ih *ImportHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(ih.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		ih.get(w, usr)
+	case http.MethodDelete:
+		ih.delete(w, req, usr)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method: %s", req.Method)))
+	}
+}
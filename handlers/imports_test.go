@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/groggygopher/oyster/register"
+	"github.com/groggygopher/oyster/session"
+	"golang.org/x/net/publicsuffix"
+)
+
+func TestImports(t *testing.T) {
+	m, err := session.CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+
+	importHdl := NewImportHandler(m)
+	srv := httptest.NewServer(importHdl)
+	defer srv.Close()
+
+	client := srv.Client()
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		t.Fatalf("cookiejar.New(): %v", err)
+	}
+	client.Jar = jar
+	urlStr := fmt.Sprintf("%s/imports", srv.URL)
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		t.Fatalf("url.Parse(%s): %v", urlStr, err)
+	}
+
+	resp, err := client.Get(urlStr)
+	if err != nil {
+		t.Fatalf("client.Get(%s): %v", urlStr, err)
+	}
+	if got, want := resp.StatusCode, http.StatusUnauthorized; got != want {
+		t.Fatalf("no login: GET /imports: got: %d, want: %d", got, want)
+	}
+
+	// Login.
+	usr, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("testManager.Login: %v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{&http.Cookie{Name: sessCookieKey, Value: token}})
+	now := time.Now()
+	batch, err := usr.Import(register.SliceSource([]*register.Transaction{{ID: "a", Date: &now}}), "test.csv", "", now)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	tests := []struct {
+		method   string
+		body     string
+		wantCode int
+	}{
+		{
+			method:   http.MethodGet,
+			wantCode: http.StatusOK,
+		},
+		{
+			method:   http.MethodDelete,
+			body:     `{"id":"missing"}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			method:   http.MethodDelete,
+			body:     fmt.Sprintf(`{"id":%q}`, batch.ID),
+			wantCode: http.StatusOK,
+		},
+		{
+			method:   http.MethodPost,
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, urlStr, bytes.NewReader([]byte(test.body)))
+		if err != nil {
+			t.Fatalf("http.NewRequest: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("client.Do(%s): %v", urlStr, err)
+		}
+		if got, want := resp.StatusCode, test.wantCode; got != want {
+			t.Errorf("%s %s: got: %d, want: %d", test.method, test.body, got, want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewNotesHandler returns a new NotesHandler with the given SessionManager.
+func NewNotesHandler(man *session.Manager) *NotesHandler {
+	return &NotesHandler{manager: man}
+}
+
+// NotesHandler sets the memo on a user's transaction.
+type NotesHandler struct {
+	manager *session.Manager
+}
+
+// ServeHTTP serves PUT requests replacing the notes of a single transaction.
+func (nh *NotesHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(nh.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if req.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method %s", req.Method)))
+		return
+	}
+
+	body := &struct {
+		ID    string `json:"id"`
+		Notes string `json:"notes"`
+	}{}
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(body); err != nil {
+		http.Error(w, "invalid notes request JSON body", http.StatusBadRequest)
+		log.Printf("error: decode notes request body: %v", err)
+		return
+	}
+	if err := usr.SetNotes(body.ID, body.Notes); err != nil {
+		writeLookupError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewPasswordHandler returns a new PasswordHandler with the given SessionManager.
+func NewPasswordHandler(man *session.Manager) *PasswordHandler {
+	return &PasswordHandler{manager: man}
+}
+
+// PasswordHandler changes the password of the logged in user.
+type PasswordHandler struct {
+	manager *session.Manager
+}
+
+func (ph *PasswordHandler) put(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	body := &struct {
+		Old string `json:"old"`
+		New string `json:"new"`
+	}{}
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(body); err != nil {
+		http.Error(w, "invalid password JSON body", http.StatusBadRequest)
+		log.Printf("error: decode password body: %v", err)
+		return
+	}
+	if len(body.New) < 4 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Password must be at least 4 characters long"))
+		return
+	}
+	changed, c, err := ph.manager.ChangePassword(usr.Name, body.Old, body.New)
+	if err != nil {
+		log.Printf("error: manager.ChangePassword(%s): %v", usr.Name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("An internal error occurred"))
+		return
+	}
+	if changed == nil {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("The current password is incorrect"))
+		return
+	}
+	// Every other session has ended, so this one carries on with a new token.
+	http.SetCookie(w, sessionCookie(req, c))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeHTTP changes the user's password on PUT, given the current and new passwords.
+func (ph *PasswordHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(ph.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodPut:
+		ph.put(w, req, usr)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method: %s", req.Method)))
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/groggygopher/oyster/session"
+	"golang.org/x/net/publicsuffix"
+)
+
+func TestPassword(t *testing.T) {
+	m, err := session.CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+
+	passwordHdl := NewPasswordHandler(m)
+	srv := httptest.NewServer(passwordHdl)
+	defer srv.Close()
+
+	client := srv.Client()
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		t.Fatalf("cookiejar.New(): %v", err)
+	}
+	client.Jar = jar
+	urlStr := fmt.Sprintf("%s/password", srv.URL)
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		t.Fatalf("url.Parse(%s): %v", urlStr, err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, urlStr, bytes.NewReader([]byte(`{"old":"test","new":"changed"}`)))
+	if err != nil {
+		t.Fatalf("http.NewRequest: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("client.Do(%s): %v", urlStr, err)
+	}
+	if got, want := resp.StatusCode, http.StatusUnauthorized; got != want {
+		t.Fatalf("no login: PUT /password: got: %d, want: %d", got, want)
+	}
+
+	// Login.
+	_, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("testManager.Login: %v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{&http.Cookie{Name: sessCookieKey, Value: token}})
+
+	tests := []struct {
+		method   string
+		body     string
+		wantCode int
+	}{
+		{
+			method:   http.MethodGet,
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		{
+			method:   http.MethodPut,
+			body:     `{"old":"test","new":"abc"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			method:   http.MethodPut,
+			body:     `{"old":"wrong","new":"changed"}`,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			method:   http.MethodPut,
+			body:     `{"old":"test","new":"changed"}`,
+			wantCode: http.StatusNoContent,
+		},
+		// The new session cookie keeps the user logged in.
+		{
+			method:   http.MethodPut,
+			body:     `{"old":"changed","new":"test"}`,
+			wantCode: http.StatusNoContent,
+		},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, urlStr, bytes.NewReader([]byte(test.body)))
+		if err != nil {
+			t.Fatalf("http.NewRequest: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("client.Do(%s): %v", urlStr, err)
+		}
+		if got, want := resp.StatusCode, test.wantCode; got != want {
+			t.Errorf("%s %s: got: %d, want: %d", test.method, test.body, got, want)
+		}
+	}
+	if m.ValidSession(token) != nil {
+		t.Error("the old session should end when the password changes")
+	}
+}
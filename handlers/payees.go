@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/groggygopher/oyster/payee"
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewPayeeHandler returns a new PayeeHandler with the given SessionManager.
+func NewPayeeHandler(man *session.Manager) *PayeeHandler {
+	return &PayeeHandler{manager: man}
+}
+
+// PayeeHandler manages a user's payee alias table.
+type PayeeHandler struct {
+	manager *session.Manager
+}
+
+func (ph *PayeeHandler) get(w http.ResponseWriter, usr *session.User) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(usr.PayeeAliases()); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+func (ph *PayeeHandler) put(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	alias := &payee.Alias{}
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(alias); err != nil {
+		http.Error(w, "invalid payee alias JSON body", http.StatusBadRequest)
+		log.Printf("error: decode payee alias body: %v", err)
+		return
+	}
+	if err := usr.SetPayeeAlias(alias); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ph *PayeeHandler) delete(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	body := &struct {
+		Match string `json:"match"`
+	}{}
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(body); err != nil {
+		http.Error(w, "invalid payee alias JSON body", http.StatusBadRequest)
+		log.Printf("error: decode payee alias body: %v", err)
+		return
+	}
+	if !usr.DeletePayeeAlias(body.Match) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("No payee alias matching '%s' exists", body.Match)))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeHTTP lists payee aliases on GET, adds or replaces one on PUT and removes one on DELETE.
+func (ph *PayeeHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(ph.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		ph.get(w, usr)
+	case http.MethodPut:
+		ph.put(w, req, usr)
+	case http.MethodDelete:
+		ph.delete(w, req, usr)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method: %s", req.Method)))
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewRecoveryHandler returns a new RecoveryHandler with the given SessionManager.
+func NewRecoveryHandler(man *session.Manager) *RecoveryHandler {
+	return &RecoveryHandler{manager: man}
+}
+
+// RecoveryHandler issues recovery codes and lets a user who has forgotten their password set a new
+// one with their recovery code.
+type RecoveryHandler struct {
+	manager *session.Manager
+}
+
+type recoveryCode struct {
+	Code string `json:"code"`
+}
+
+func (rh *RecoveryHandler) post(w http.ResponseWriter, req *http.Request) {
+	usr := RequestUser(rh.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	code, err := rh.manager.NewRecoveryCode(usr)
+	if err != nil {
+		log.Printf("error: manager.NewRecoveryCode(%s): %v", usr.Name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("An internal error occurred"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(&recoveryCode{Code: code}); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+func (rh *RecoveryHandler) put(w http.ResponseWriter, req *http.Request) {
+	body := &struct {
+		Name     string `json:"name"`
+		Code     string `json:"code"`
+		Password string `json:"password"`
+	}{}
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(body); err != nil {
+		http.Error(w, "invalid recovery JSON body", http.StatusBadRequest)
+		log.Printf("error: decode recovery body: %v", err)
+		return
+	}
+	if len(body.Password) < 4 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Password must be at least 4 characters long"))
+		return
+	}
+	usr, c, code, err := rh.manager.Recover(body.Name, body.Code, body.Password)
+	if err != nil {
+		log.Printf("error: manager.Recover(%s): %v", body.Name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("An internal error occurred"))
+		return
+	}
+	if usr == nil {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("The recovery code is incorrect"))
+		return
+	}
+	http.SetCookie(w, sessionCookie(req, c))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(&recoveryCode{Code: code}); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+// ServeHTTP replaces the logged in user's recovery code on POST, returning the new code. PUT
+// requests need no session: given a user name, recovery code and new password, they set the
+// password, log the user in and return their next recovery code, since each code is used once.
+func (rh *RecoveryHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	switch req.Method {
+	case http.MethodPost:
+		rh.post(w, req)
+	case http.MethodPut:
+		rh.put(w, req)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method: %s", req.Method)))
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/groggygopher/oyster/session"
+	"golang.org/x/net/publicsuffix"
+)
+
+func TestRecovery(t *testing.T) {
+	m, err := session.CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+
+	recoveryHdl := NewRecoveryHandler(m)
+	srv := httptest.NewServer(recoveryHdl)
+	defer srv.Close()
+
+	client := srv.Client()
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		t.Fatalf("cookiejar.New(): %v", err)
+	}
+	client.Jar = jar
+	urlStr := fmt.Sprintf("%s/recovery", srv.URL)
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		t.Fatalf("url.Parse(%s): %v", urlStr, err)
+	}
+
+	resp, err := client.Post(urlStr, "application/json", nil)
+	if err != nil {
+		t.Fatalf("client.Post(%s): %v", urlStr, err)
+	}
+	if got, want := resp.StatusCode, http.StatusUnauthorized; got != want {
+		t.Fatalf("no login: POST /recovery: got: %d, want: %d", got, want)
+	}
+
+	// Login.
+	_, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("testManager.Login: %v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{&http.Cookie{Name: sessCookieKey, Value: token}})
+
+	resp, err = client.Post(urlStr, "application/json", nil)
+	if err != nil {
+		t.Fatalf("client.Post(%s): %v", urlStr, err)
+	}
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("POST /recovery: got: %d, want: %d", got, want)
+	}
+	code := &recoveryCode{}
+	if err := json.NewDecoder(resp.Body).Decode(code); err != nil {
+		t.Fatalf("json.Decode: %v", err)
+	}
+	resp.Body.Close()
+
+	// Recovery needs no session.
+	jar.SetCookies(u, []*http.Cookie{&http.Cookie{Name: sessCookieKey, MaxAge: -1}})
+	tests := []struct {
+		method   string
+		body     string
+		wantCode int
+	}{
+		{
+			method:   http.MethodGet,
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		{
+			method:   http.MethodPut,
+			body:     fmt.Sprintf(`{"name":"test","code":%q,"password":"abc"}`, code.Code),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			method:   http.MethodPut,
+			body:     `{"name":"test","code":"AAAA-BBBB","password":"changed"}`,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			method:   http.MethodPut,
+			body:     fmt.Sprintf(`{"name":"test","code":%q,"password":"changed"}`, code.Code),
+			wantCode: http.StatusOK,
+		},
+		// Each code is used once.
+		{
+			method:   http.MethodPut,
+			body:     fmt.Sprintf(`{"name":"test","code":%q,"password":"again"}`, code.Code),
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, urlStr, bytes.NewReader([]byte(test.body)))
+		if err != nil {
+			t.Fatalf("http.NewRequest: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("client.Do(%s): %v", urlStr, err)
+		}
+		if got, want := resp.StatusCode, test.wantCode; got != want {
+			t.Errorf("%s %s: got: %d, want: %d", test.method, test.body, got, want)
+		}
+	}
+	if usr, _, _, err := m.Login("test", "changed"); err != nil || usr == nil {
+		t.Errorf("Login with the recovered password: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/groggygopher/oyster/recurring"
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewRecurringHandler returns a new RecurringHandler with the given SessionManager.
+func NewRecurringHandler(man *session.Manager) *RecurringHandler {
+	return &RecurringHandler{manager: man}
+}
+
+// RecurringHandler serves the recurring transaction series found in a user's history.
+type RecurringHandler struct {
+	manager *session.Manager
+}
+
+// ServeHTTP serves GET queries for returning all of a user's recurring series.
+func (rh *RecurringHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(rh.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method %s", req.Method)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(recurring.Analyze(usr.Transactions(), time.Now())); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
@@ -44,6 +44,15 @@ func readRule(r io.Reader) (*rule.Rule, error) {
 	return rule, nil
 }
 
+func checkCategory(w http.ResponseWriter, usr *session.User, r *rule.Rule) bool {
+	if r.Category == "" || usr.HasCategory(r.Category) {
+		return true
+	}
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write([]byte(fmt.Sprintf("Unknown category '%s'", r.Category)))
+	return false
+}
+
 func (rh *RuleHandler) post(w http.ResponseWriter, req *http.Request) {
 	usr := RequestUser(rh.manager, req)
 	if usr == nil {
@@ -57,12 +66,21 @@ func (rh *RuleHandler) post(w http.ResponseWriter, req *http.Request) {
 		log.Printf("error: readRule: %v", err)
 		return
 	}
+	if !checkCategory(w, usr, r) {
+		return
+	}
+	if err := r.Validate(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
 	added := usr.RuleManager().AddRule(r)
 	if !added {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(fmt.Sprintf("A rule with name '%s' already exists", r.Name)))
 		return
 	}
+	usr.MarkDirty()
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -79,7 +97,15 @@ func (rh *RuleHandler) put(w http.ResponseWriter, req *http.Request) {
 		log.Printf("error: readRule: %v", err)
 		return
 	}
-	usr.RuleManager().UpsertRule(r.Name, r)
+	if !checkCategory(w, usr, r) {
+		return
+	}
+	if err := usr.RuleManager().UpsertRule(r.Name, r); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	usr.MarkDirty()
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -102,6 +128,7 @@ func (rh *RuleHandler) delete(w http.ResponseWriter, req *http.Request) {
 		w.Write([]byte(fmt.Sprintf("No rule with name '%s' exists", r.Name)))
 		return
 	}
+	usr.MarkDirty()
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -36,7 +36,7 @@ func TestRules(t *testing.T) {
 	}
 
 	// Login.
-	_, token, err := m.Login("test", "test")
+	_, token, _, err := m.Login("test", "test")
 	if err != nil {
 		t.Fatalf("testManager.Login: %v", err)
 	}
@@ -72,6 +72,21 @@ func TestRules(t *testing.T) {
 			body:     `{"name":"test"}`,
 			wantCode: http.StatusNoContent,
 		},
+		{
+			method:   http.MethodPut,
+			body:     `{"name":"test","description":"test","examples":[{"transaction":{"description":"test"},"match":false}]}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			method:   http.MethodPost,
+			body:     `{"name":"test2","description":"test","examples":[{"transaction":{"description":"other"},"match":true}]}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			method:   http.MethodPut,
+			body:     `{"name":"test","description":"test","examples":[{"transaction":{"description":"test"},"match":true}]}`,
+			wantCode: http.StatusNoContent,
+		},
 		{
 			method:   http.MethodDelete,
 			body:     `{"name":"test"}`,
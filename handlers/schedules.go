@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/groggygopher/oyster/recurring"
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewScheduleHandler returns a new ScheduleHandler with the given SessionManager.
+func NewScheduleHandler(man *session.Manager) *ScheduleHandler {
+	return &ScheduleHandler{manager: man}
+}
+
+// ScheduleHandler manages a user's scheduled transactions.
+type ScheduleHandler struct {
+	manager *session.Manager
+}
+
+func (sh *ScheduleHandler) get(w http.ResponseWriter, usr *session.User) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(usr.Schedules()); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+func (sh *ScheduleHandler) post(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	s := &recurring.Schedule{}
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(s); err != nil {
+		http.Error(w, "invalid JSON Schedule object", http.StatusBadRequest)
+		log.Printf("error: decode schedule: %v", err)
+		return
+	}
+	if err := usr.AddSchedule(s); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(s); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+func (sh *ScheduleHandler) delete(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	body := &struct {
+		ID string `json:"id"`
+	}{}
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(body); err != nil {
+		http.Error(w, "invalid schedule request JSON body", http.StatusBadRequest)
+		log.Printf("error: decode schedule request body: %v", err)
+		return
+	}
+	if !usr.DeleteSchedule(body.ID) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("No schedule with ID '%s' exists", body.ID)))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeHTTP lists scheduled transactions on GET, adds one on POST and removes one on DELETE.
+func (sh *ScheduleHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(sh.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		sh.get(w, usr)
+	case http.MethodPost:
+		sh.post(w, req, usr)
+	case http.MethodDelete:
+		sh.delete(w, req, usr)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method: %s", req.Method)))
+	}
+}
@@ -20,6 +20,20 @@ func getCookie(req *http.Request) (string, bool) {
 	return "", false
 }
 
+// sessionCookie returns the cookie that carries the given session token in response to req. It is
+// hidden from scripts and never sent on requests from other sites. When req came over HTTPS, it is
+// only ever sent over HTTPS.
+func sessionCookie(req *http.Request, token string) *http.Cookie {
+	return &http.Cookie{
+		Name:     sessCookieKey,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   req.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
 // RequestUser returns the authenticated User given by the request's cookies.
 func RequestUser(man *session.Manager, req *http.Request) *session.User {
 	c, ok := getCookie(req)
@@ -65,20 +79,22 @@ func (sh *SessionHandler) register(w http.ResponseWriter, req *http.Request) {
 		log.Printf("error: decode user request body: %v", err)
 		return
 	}
-	usr, c, err := sh.manager.Register(body.Name, body.Password)
+	usr, c, code, err := sh.manager.Register(body.Name, body.Password)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
 		return
 	}
-	http.SetCookie(w, &http.Cookie{
-		Name:  sessCookieKey,
-		Value: c,
-	})
+	http.SetCookie(w, sessionCookie(req, c))
+	// The recovery code is only ever shown here, so the user must write it down.
+	resp := &struct {
+		*session.User
+		RecoveryCode string `json:"recoveryCode"`
+	}{usr, code}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	enc := json.NewEncoder(w)
-	if err := enc.Encode(usr); err != nil {
+	if err := enc.Encode(resp); err != nil {
 		log.Printf("error: encode user %v: %v", usr, err)
 	}
 }
@@ -94,7 +110,7 @@ func (sh *SessionHandler) login(w http.ResponseWriter, req *http.Request) {
 		log.Printf("error: decode user request body: %v", err)
 		return
 	}
-	usr, c, err := sh.manager.Login(body.Name, body.Password)
+	usr, c, code, err := sh.manager.Login(body.Name, body.Password)
 	if err != nil {
 		log.Printf("error: manager.Login(%s): %v", body.Name, err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -105,14 +121,16 @@ func (sh *SessionHandler) login(w http.ResponseWriter, req *http.Request) {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
-	http.SetCookie(w, &http.Cookie{
-		Name:  sessCookieKey,
-		Value: c,
-	})
+	http.SetCookie(w, sessionCookie(req, c))
+	// Upgrading an older save file sets a recovery code, which is only ever shown here.
+	resp := &struct {
+		*session.User
+		RecoveryCode string `json:"recoveryCode,omitempty"`
+	}{usr, code}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	enc := json.NewEncoder(w)
-	if err := enc.Encode(usr); err != nil {
+	if err := enc.Encode(resp); err != nil {
 		log.Printf("error: encode user %v: %v", usr, err)
 	}
 }
@@ -128,7 +146,9 @@ func (sh *SessionHandler) logout(w http.ResponseWriter, req *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	http.SetCookie(w, &http.Cookie{Name: sessCookieKey})
+	cookie := sessionCookie(req, "")
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"bytes"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/cookiejar"
@@ -56,6 +58,16 @@ func TestRegister(t *testing.T) {
 	if got := resp.Cookies(); len(got) == 0 {
 		t.Error("register response should have cookies")
 	}
+	body := &struct {
+		Name         string `json:"name"`
+		RecoveryCode string `json:"recoveryCode"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(body); err != nil {
+		t.Fatalf("json.Decode: %v", err)
+	}
+	if body.Name != "newtest" || body.RecoveryCode == "" {
+		t.Errorf("register response: got: %+v, want the user and a recovery code", body)
+	}
 }
 
 func TestSession(t *testing.T) {
@@ -64,8 +76,9 @@ func TestSession(t *testing.T) {
 		t.Fatalf("CreateTestManager: %v", err)
 	}
 
+	// The session cookie is Secure, so it is only sent over TLS.
 	sessHdl := NewSessionHandler(m)
-	srv := httptest.NewServer(sessHdl)
+	srv := httptest.NewTLSServer(sessHdl)
 	defer srv.Close()
 
 	client := srv.Client()
@@ -123,6 +136,8 @@ func TestSession(t *testing.T) {
 	}
 	if got := resp.Cookies(); len(got) == 0 {
 		t.Error("login response should have cookies")
+	} else if c := got[0]; !c.HttpOnly || !c.Secure || c.SameSite != http.SameSiteStrictMode {
+		t.Errorf("session cookie: got: %+v, want HttpOnly, Secure and SameSite=Strict", c)
 	}
 	// GET /session with good session.
 	resp, err = client.Get(url)
@@ -149,3 +164,14 @@ func TestSession(t *testing.T) {
 	// Check session is no longer valid.
 	checkNoUser("after logout")
 }
+
+func TestSessionCookieSecure(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/session", nil)
+	if sessionCookie(req, "token").Secure {
+		t.Error("a cookie set over plain HTTP should not be Secure")
+	}
+	req.TLS = &tls.ConnectionState{}
+	if !sessionCookie(req, "token").Secure {
+		t.Error("a cookie set over HTTPS should be Secure")
+	}
+}
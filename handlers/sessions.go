@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewSessionsHandler returns a new SessionsHandler with the given SessionManager.
+func NewSessionsHandler(man *session.Manager) *SessionsHandler {
+	return &SessionsHandler{manager: man}
+}
+
+// SessionsHandler lists and revokes the active sessions of the logged in user, such as those of
+// their other devices.
+type SessionsHandler struct {
+	manager *session.Manager
+}
+
+func (sh *SessionsHandler) get(w http.ResponseWriter, token string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(sh.manager.Sessions(token)); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+func (sh *SessionsHandler) delete(w http.ResponseWriter, req *http.Request, token string) {
+	body := &struct {
+		ID string `json:"id"`
+	}{}
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(body); err != nil {
+		http.Error(w, "invalid session JSON body", http.StatusBadRequest)
+		log.Printf("error: decode session body: %v", err)
+		return
+	}
+	if err := sh.manager.RevokeSession(token, body.ID); err != nil {
+		if err == session.ErrNoSession {
+			writeLookupError(w, err)
+			return
+		}
+		log.Printf("error: manager.RevokeSession: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("An internal error occurred"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeHTTP lists the user's sessions on GET and ends the session with the given ID on DELETE.
+func (sh *SessionsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(sh.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	token, _ := getCookie(req)
+
+	switch req.Method {
+	case http.MethodGet:
+		sh.get(w, token)
+	case http.MethodDelete:
+		sh.delete(w, req, token)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method: %s", req.Method)))
+	}
+}
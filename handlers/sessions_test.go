@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/groggygopher/oyster/session"
+	"golang.org/x/net/publicsuffix"
+)
+
+func TestSessions(t *testing.T) {
+	m, err := session.CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+
+	sessionsHdl := NewSessionsHandler(m)
+	srv := httptest.NewServer(sessionsHdl)
+	defer srv.Close()
+
+	client := srv.Client()
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		t.Fatalf("cookiejar.New(): %v", err)
+	}
+	client.Jar = jar
+	urlStr := fmt.Sprintf("%s/sessions", srv.URL)
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		t.Fatalf("url.Parse(%s): %v", urlStr, err)
+	}
+
+	resp, err := client.Get(urlStr)
+	if err != nil {
+		t.Fatalf("client.Get(%s): %v", urlStr, err)
+	}
+	if got, want := resp.StatusCode, http.StatusUnauthorized; got != want {
+		t.Fatalf("no login: GET /sessions: got: %d, want: %d", got, want)
+	}
+
+	// Login twice, as if from two devices.
+	_, other, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("testManager.Login: %v", err)
+	}
+	_, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("testManager.Login: %v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{&http.Cookie{Name: sessCookieKey, Value: token}})
+
+	resp, err = client.Get(urlStr)
+	if err != nil {
+		t.Fatalf("client.Get(%s): %v", urlStr, err)
+	}
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("GET /sessions: got: %d, want: %d", got, want)
+	}
+	var infos []*session.SessionInfo
+	if err := json.NewDecoder(resp.Body).Decode(&infos); err != nil {
+		t.Fatalf("json.Decode: %v", err)
+	}
+	resp.Body.Close()
+	if got, want := len(infos), 2; got != want {
+		t.Fatalf("sessions: got: %d, want: %d", got, want)
+	}
+
+	tests := []struct {
+		method   string
+		body     string
+		wantCode int
+	}{
+		{
+			method:   http.MethodDelete,
+			body:     `{"id":"SESS-missing"}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			method:   http.MethodDelete,
+			body:     fmt.Sprintf(`{"id":%q}`, infos[0].ID),
+			wantCode: http.StatusNoContent,
+		},
+		{
+			method:   http.MethodPost,
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, urlStr, bytes.NewReader([]byte(test.body)))
+		if err != nil {
+			t.Fatalf("http.NewRequest: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("client.Do(%s): %v", urlStr, err)
+		}
+		if got, want := resp.StatusCode, test.wantCode; got != want {
+			t.Errorf("%s %s: got: %d, want: %d", test.method, test.body, got, want)
+		}
+	}
+	if m.ValidSession(other) != nil {
+		t.Error("the revoked session should end")
+	}
+}
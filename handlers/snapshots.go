@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewSnapshotHandler returns a new SnapshotHandler with the given SessionManager.
+func NewSnapshotHandler(man *session.Manager) *SnapshotHandler {
+	return &SnapshotHandler{manager: man}
+}
+
+// SnapshotHandler lists, previews and restores the snapshots of a user's data.
+type SnapshotHandler struct {
+	manager *session.Manager
+}
+
+func (sh *SnapshotHandler) get(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	var resp interface{}
+	if id := req.URL.Query().Get("id"); id != "" {
+		sum, err := sh.manager.PreviewSnapshot(usr, id)
+		if err != nil {
+			log.Printf("error: PreviewSnapshot(%s): %v", id, err)
+			if err == session.ErrNoSnapshot {
+				writeLookupError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("An internal error occurred"))
+			return
+		}
+		resp = sum
+	} else {
+		snaps, err := sh.manager.Snapshots(usr)
+		if err != nil {
+			log.Printf("error: manager.Snapshots: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("An internal error occurred"))
+			return
+		}
+		resp = snaps
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(resp); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+func (sh *SnapshotHandler) post(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	body := &struct {
+		ID string `json:"id"`
+	}{}
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(body); err != nil {
+		http.Error(w, "invalid snapshot JSON body", http.StatusBadRequest)
+		log.Printf("error: decode snapshot body: %v", err)
+		return
+	}
+	if err := sh.manager.RestoreSnapshot(usr, body.ID); err != nil {
+		log.Printf("error: RestoreSnapshot(%s): %v", body.ID, err)
+		if err == session.ErrNoSnapshot {
+			writeLookupError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("An internal error occurred"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeHTTP lists the user's snapshots, newest first, on GET, or summarizes the one given by the
+// id query parameter. POST restores the snapshot with the given ID as the user's current data.
+func (sh *SnapshotHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(sh.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		sh.get(w, req, usr)
+	case http.MethodPost:
+		sh.post(w, req, usr)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method: %s", req.Method)))
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/groggygopher/oyster/session"
+	"golang.org/x/net/publicsuffix"
+)
+
+func TestSnapshots(t *testing.T) {
+	m, err := session.CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+
+	snapshotHdl := NewSnapshotHandler(m)
+	srv := httptest.NewServer(snapshotHdl)
+	defer srv.Close()
+
+	client := srv.Client()
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		t.Fatalf("cookiejar.New(): %v", err)
+	}
+	client.Jar = jar
+	urlStr := fmt.Sprintf("%s/snapshots", srv.URL)
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		t.Fatalf("url.Parse(%s): %v", urlStr, err)
+	}
+
+	resp, err := client.Get(urlStr)
+	if err != nil {
+		t.Fatalf("client.Get(%s): %v", urlStr, err)
+	}
+	if got, want := resp.StatusCode, http.StatusUnauthorized; got != want {
+		t.Fatalf("no login: GET /snapshots: got: %d, want: %d", got, want)
+	}
+
+	usr, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("testManager.Login: %v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{&http.Cookie{Name: sessCookieKey, Value: token}})
+
+	resp, err = client.Get(urlStr)
+	if err != nil {
+		t.Fatalf("client.Get(%s): %v", urlStr, err)
+	}
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("GET /snapshots: got: %d, want: %d", got, want)
+	}
+	var snaps []*session.Snapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snaps); err != nil {
+		t.Fatalf("json.Decode: %v", err)
+	}
+	resp.Body.Close()
+	if got, want := len(snaps), 1; got != want {
+		t.Fatalf("snapshots: got: %d, want: %d", got, want)
+	}
+	if err := usr.AddCategory("Bad"); err != nil {
+		t.Fatalf("AddCategory: %v", err)
+	}
+
+	tests := []struct {
+		method   string
+		query    string
+		body     string
+		wantCode int
+	}{
+		{
+			method:   http.MethodGet,
+			query:    "?id=" + snaps[0].ID,
+			wantCode: http.StatusOK,
+		},
+		{
+			method:   http.MethodGet,
+			query:    "?id=SNAP-missing",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			method:   http.MethodPost,
+			body:     `{"id":"SNAP-missing"}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			method:   http.MethodPost,
+			body:     `not json`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			method:   http.MethodPost,
+			body:     fmt.Sprintf(`{"id":%q}`, snaps[0].ID),
+			wantCode: http.StatusNoContent,
+		},
+		{
+			method:   http.MethodDelete,
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, urlStr+test.query, bytes.NewReader([]byte(test.body)))
+		if err != nil {
+			t.Fatalf("http.NewRequest: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("client.Do(%s): %v", urlStr, err)
+		}
+		if got, want := resp.StatusCode, test.wantCode; got != want {
+			t.Errorf("%s %s %s: got: %d, want: %d", test.method, test.query, test.body, got, want)
+		}
+	}
+	if usr.HasCategory("Bad") {
+		t.Error("restoring the snapshot should undo the change")
+	}
+}
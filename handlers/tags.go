@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewTagHandler returns a new TagHandler with the given SessionManager.
+func NewTagHandler(man *session.Manager) *TagHandler {
+	return &TagHandler{manager: man}
+}
+
+// TagHandler adds and removes tags on a user's transactions in bulk.
+type TagHandler struct {
+	manager *session.Manager
+}
+
+func (th *TagHandler) get(w http.ResponseWriter, usr *session.User) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(usr.TagTotals()); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+func (th *TagHandler) modify(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	body := &struct {
+		IDs  []string `json:"ids"`
+		Tags []string `json:"tags"`
+	}{}
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(body); err != nil {
+		http.Error(w, "invalid tag request JSON body", http.StatusBadRequest)
+		log.Printf("error: decode tag request body: %v", err)
+		return
+	}
+
+	var modified int
+	var err error
+	if req.Method == http.MethodPost {
+		modified, err = usr.TagTransactions(body.IDs, body.Tags)
+	} else {
+		modified, err = usr.UntagTransactions(body.IDs, body.Tags)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	resp := &struct {
+		Modified int `json:"modified"`
+	}{
+		Modified: modified,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(resp); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+// ServeHTTP serves per-tag totals on GET, adds tags to transactions on POST and removes tags from
+// transactions on DELETE.
+func (th *TagHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(th.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		th.get(w, usr)
+	case http.MethodPost, http.MethodDelete:
+		th.modify(w, req, usr)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method: %s", req.Method)))
+	}
+}
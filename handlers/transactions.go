@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/groggygopher/oyster/register"
 	"github.com/groggygopher/oyster/session"
 )
 
@@ -19,7 +20,8 @@ type TransactionsHandler struct {
 	manager *session.Manager
 }
 
-// ServeHTTP serves GET queries for returning all of a user's transactions.
+// ServeHTTP serves GET queries for returning all of a user's transactions. Passing one or more tag
+// query parameters returns only the transactions with all of those tags.
 func (th *TransactionsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 
@@ -35,10 +37,17 @@ func (th *TransactionsHandler) ServeHTTP(w http.ResponseWriter, req *http.Reques
 		return
 	}
 
+	var trans []*register.Transaction
+	if tags := req.URL.Query()["tag"]; len(tags) > 0 {
+		trans = usr.TaggedTransactions(tags)
+	} else {
+		trans = usr.Transactions()
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	enc := json.NewEncoder(w)
-	if err := enc.Encode(usr.Transactions()); err != nil {
+	if err := enc.Encode(trans); err != nil {
 		log.Printf("error: json.Marshal: %v", err)
 		return
 	}
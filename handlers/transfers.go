@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/groggygopher/oyster/register"
+	"github.com/groggygopher/oyster/session"
+)
+
+// NewTransferHandler returns a new TransferHandler with the given SessionManager.
+func NewTransferHandler(man *session.Manager) *TransferHandler {
+	return &TransferHandler{manager: man}
+}
+
+// TransferHandler suggests, confirms and unlinks transfers between a user's accounts.
+type TransferHandler struct {
+	manager *session.Manager
+}
+
+func (th *TransferHandler) get(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	days, err := queryDays(req, register.DefaultTransferDays)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	resp := &struct {
+		Linked    []*register.TransferPair `json:"linked"`
+		Suggested []*register.TransferPair `json:"suggested"`
+	}{
+		Linked:    usr.Transfers(),
+		Suggested: usr.SuggestTransfers(days),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(resp); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
+
+func (th *TransferHandler) modify(w http.ResponseWriter, req *http.Request, usr *session.User) {
+	body := &struct {
+		ID   string `json:"id"`
+		From string `json:"from"`
+		To   string `json:"to"`
+	}{}
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(body); err != nil {
+		http.Error(w, "invalid transfer request JSON body", http.StatusBadRequest)
+		log.Printf("error: decode transfer request body: %v", err)
+		return
+	}
+
+	var err error
+	if req.Method == http.MethodPost {
+		err = usr.LinkTransfer(body.From, body.To)
+	} else {
+		err = usr.UnlinkTransfer(body.ID)
+	}
+	if err != nil {
+		writeLookupError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeHTTP serves linked and suggested transfers on GET, confirms a suggested pair on POST and
+// unlinks a pair on DELETE. The days query parameter bounds how far apart suggested halves may be.
+func (th *TransferHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(th.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		th.get(w, req, usr)
+	case http.MethodPost, http.MethodDelete:
+		th.modify(w, req, usr)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method: %s", req.Method)))
+	}
+}
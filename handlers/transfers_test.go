@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/groggygopher/oyster/register"
+	"github.com/groggygopher/oyster/session"
+	"golang.org/x/net/publicsuffix"
+)
+
+func TestTransfers(t *testing.T) {
+	m, err := session.CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+
+	transferHdl := NewTransferHandler(m)
+	srv := httptest.NewServer(transferHdl)
+	defer srv.Close()
+
+	client := srv.Client()
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		t.Fatalf("cookiejar.New(): %v", err)
+	}
+	client.Jar = jar
+	urlStr := fmt.Sprintf("%s/transfers", srv.URL)
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		t.Fatalf("url.Parse(%s): %v", urlStr, err)
+	}
+
+	// Login.
+	usr, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("testManager.Login: %v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{&http.Cookie{Name: sessCookieKey, Value: token}})
+	now := time.Now()
+	usr.ImportTransactions([]*register.Transaction{
+		{ID: "out", Description: "TRANSFER TO SAVINGS", Amount: -10, Date: &now},
+		{ID: "in", Description: "DEPOSIT", Amount: 10, Date: &now},
+	})
+
+	tests := []struct {
+		method   string
+		query    string
+		body     string
+		wantCode int
+	}{
+		// Order matters!
+		{
+			method:   http.MethodGet,
+			wantCode: http.StatusOK,
+		},
+		{
+			method:   http.MethodGet,
+			query:    "?days=-1",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			method:   http.MethodPost,
+			body:     `{"from":"out","to":"missing"}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			method:   http.MethodPost,
+			body:     `{"from":"out","to":"in"}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			method:   http.MethodDelete,
+			body:     `{"id":"out"}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			method:   http.MethodDelete,
+			body:     `{"id":"out"}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, urlStr+test.query, bytes.NewReader([]byte(test.body)))
+		if err != nil {
+			t.Fatalf("http.NewRequest: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("client.Do(%s): %v", urlStr, err)
+		}
+		if got, want := resp.StatusCode, test.wantCode; got != want {
+			t.Errorf("%s %s %s: got: %d, want: %d", test.method, test.query, test.body, got, want)
+		}
+	}
+}
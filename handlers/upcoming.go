@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/groggygopher/oyster/session"
+)
+
+const (
+	defaultUpcomingDays = 30
+	// maxQueryDays bounds the days query parameter, since the work of a request grows with it.
+	maxQueryDays = 730
+)
+
+// queryDays returns the days query parameter of the request, from 0 to maxQueryDays, or def if it
+// is unset.
+func queryDays(req *http.Request, def int) (int, error) {
+	d := req.URL.Query().Get("days")
+	if d == "" {
+		return def, nil
+	}
+	days, err := strconv.Atoi(d)
+	if err != nil || days < 0 || days > maxQueryDays {
+		return 0, fmt.Errorf("invalid days: %s", d)
+	}
+	return days, nil
+}
+
+// NewUpcomingHandler returns a new UpcomingHandler with the given SessionManager.
+func NewUpcomingHandler(man *session.Manager) *UpcomingHandler {
+	return &UpcomingHandler{manager: man}
+}
+
+// UpcomingHandler serves a user's predicted and scheduled transactions for the coming days.
+type UpcomingHandler struct {
+	manager *session.Manager
+}
+
+// ServeHTTP serves GET queries for the upcoming transactions of the next days query parameter
+// days.
+func (uh *UpcomingHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	usr := RequestUser(uh.manager, req)
+	if usr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf("Unsupported method %s", req.Method)))
+		return
+	}
+
+	days, err := queryDays(req, defaultUpcomingDays)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(usr.Upcoming(time.Now(), days)); err != nil {
+		log.Printf("error: json.Encode: %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/groggygopher/oyster/register"
 	"github.com/groggygopher/oyster/session"
@@ -15,12 +16,59 @@ func NewUploadHandler(man *session.Manager) *UploadHandler {
 	return &UploadHandler{manager: man}
 }
 
-// UploadHandler handles transactions imports with CSV.
+// UploadHandler handles transactions imports with CSV, camt.053 and MT940 statements.
 type UploadHandler struct {
 	manager *session.Manager
 }
 
-// ServeHTTP handles importing the uploaded transactions and returning the status.
+// statement is an uploaded file of transactions.
+type statement interface {
+	register.Source
+	RowErrors() []*register.RowError
+	SkippedRows() int
+}
+
+// openStatement returns a reader for the uploaded file in the format named by the format query
+// parameter, csv by default. The CSV date and number format is returned for CSV files.
+func openStatement(req *http.Request, lenient bool) (statement, *register.CSVFormat, error) {
+	q := req.URL.Query()
+	switch format := q.Get("format"); format {
+	case "", "csv":
+		override := register.CSVFormat{
+			DateLayout: q.Get("dateLayout"),
+			Decimal:    q.Get("decimal"),
+			Thousands:  q.Get("thousands"),
+			Negative:   q.Get("negative"),
+		}
+		rdr, err := register.NewReader(req.Body, override)
+		if err != nil {
+			return nil, nil, err
+		}
+		rdr.Lenient = lenient
+		csvFormat := rdr.Format()
+		return rdr, &csvFormat, nil
+	case "camt053":
+		rdr := register.NewCamtReader(req.Body)
+		rdr.Lenient = lenient
+		return rdr, nil, nil
+	case "mt940":
+		rdr := register.NewMT940Reader(req.Body)
+		rdr.Lenient = lenient
+		return rdr, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// ServeHTTP handles importing the uploaded transactions and returning the status. The format query
+// parameter selects csv, the default, camt053 or mt940 statements. The optional account query
+// parameter names the account the transactions belong to, and filename names the uploaded file in
+// the recorded import batch. With preview=true, nothing is imported, and the number of
+// transactions that would be imported is returned with a sample of them. Rows that cannot be
+// parsed are counted in skipped and the first of them reported in the errors of the response, and
+// nothing is imported unless lenient=true, which imports the good rows.
+// The CSV format of dates and amounts is detected and reported, and the dateLayout, decimal,
+// thousands and negative query parameters override what is detected.
 func (uh *UploadHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 
@@ -36,22 +84,54 @@ func (uh *UploadHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	trans, err := register.ReadAllTransactions(req.Body)
+	q := req.URL.Query()
+	preview := q.Get("preview") == "true"
+	rdr, csvFormat, err := openStatement(req, preview || q.Get("lenient") == "true")
 	if err != nil {
-		log.Printf("error: register.ReadAllTransactions: %v", err)
+		log.Printf("error: openStatement: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("There was an error. No data was imported."))
+		w.Write([]byte(fmt.Sprintf("There was an error. No data was imported: %v", err)))
 		return
 	}
-	imported := usr.ImportTransactions(trans)
 
 	resp := &struct {
-		Uploaded int `json:"uploaded"`
-		Imported int `json:"imported"`
+		Batch        string                  `json:"batch,omitempty"`
+		Format       *register.CSVFormat     `json:"format,omitempty"`
+		Uploaded     int                     `json:"uploaded"`
+		Imported     int                     `json:"imported"`
+		Skipped      int                     `json:"skipped"`
+		Errors       []*register.RowError    `json:"errors,omitempty"`
+		Transactions []*register.Transaction `json:"transactions,omitempty"`
 	}{
-		Uploaded: len(trans),
-		Imported: imported,
+		Format: csvFormat,
+	}
+	status := http.StatusOK
+	if preview {
+		var p *session.ImportPreview
+		if p, err = usr.PreviewImport(rdr, q.Get("account")); err == nil {
+			resp.Uploaded = p.Rows
+			resp.Imported = p.New
+			resp.Transactions = p.Sample
+		}
+	} else {
+		var batch *session.ImportBatch
+		if batch, err = usr.Import(rdr, q.Get("filename"), q.Get("account"), time.Now()); err == nil {
+			resp.Batch = batch.ID
+			resp.Uploaded = batch.Rows
+			resp.Imported = batch.Imported
+		}
+	}
+	switch {
+	case err == register.ErrMalformedRows:
+		status = http.StatusBadRequest
+	case err != nil:
+		log.Printf("error: import: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("There was an error. No data was imported."))
+		return
 	}
+	resp.Skipped = rdr.SkippedRows()
+	resp.Errors = rdr.RowErrors()
 
 	jsonEnc, err := json.Marshal(resp)
 	if err != nil {
@@ -59,6 +139,6 @@ func (uh *UploadHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		log.Printf("error: json.Marshal(%v): %v", resp, err)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(status)
 	w.Write(jsonEnc)
 }
@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
+	"github.com/groggygopher/oyster/register"
 	"github.com/groggygopher/oyster/session"
 	"golang.org/x/net/publicsuffix"
 )
@@ -54,3 +58,159 @@ func TestUpload(t *testing.T) {
 		t.Fatalf("after login: GET /upload: got: %d, want: %d", got, want)
 	}
 }
+
+func TestUploadRowErrors(t *testing.T) {
+	m, err := session.CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+
+	uploadHdl := NewUploadHandler(m)
+	srv := httptest.NewServer(uploadHdl)
+	defer srv.Close()
+
+	client := srv.Client()
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		t.Fatalf("cookiejar.New(): %v", err)
+	}
+	client.Jar = jar
+	urlStr := fmt.Sprintf("%s/upload", srv.URL)
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		t.Fatalf("url.Parse(%s): %v", urlStr, err)
+	}
+
+	// Login.
+	usr, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("testManager.Login: %v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{&http.Cookie{Name: sessCookieKey, Value: token}})
+
+	const csv = "Date,No.,Description,Debit,Credit\n5/21/2018,,COFFEE,-5.50,\n5/32/2018,,BAD DATE,-1.00,\n"
+	tests := []struct {
+		query        string
+		wantCode     int
+		wantImported int
+		wantErrors   int
+	}{
+		// Order matters!
+		{
+			query:      "",
+			wantCode:   http.StatusBadRequest,
+			wantErrors: 1,
+		},
+		{
+			query:        "?preview=true",
+			wantCode:     http.StatusOK,
+			wantImported: 1,
+			wantErrors:   1,
+		},
+		{
+			query:        "?lenient=true",
+			wantCode:     http.StatusOK,
+			wantImported: 1,
+			wantErrors:   1,
+		},
+	}
+
+	for _, test := range tests {
+		resp, err := client.Post(urlStr+test.query, "text/csv", strings.NewReader(csv))
+		if err != nil {
+			t.Fatalf("client.Post(%s): %v", urlStr, err)
+		}
+		if got, want := resp.StatusCode, test.wantCode; got != want {
+			t.Errorf("POST %s: got: %d, want: %d", test.query, got, want)
+		}
+		body := &struct {
+			Imported int                  `json:"imported"`
+			Errors   []*register.RowError `json:"errors"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(body); err != nil {
+			t.Fatalf("POST %s: json.Decode: %v", test.query, err)
+		}
+		resp.Body.Close()
+		if got, want := body.Imported, test.wantImported; got != want {
+			t.Errorf("POST %s: imported: got: %d, want: %d", test.query, got, want)
+		}
+		if got, want := len(body.Errors), test.wantErrors; got != want {
+			t.Errorf("POST %s: errors: got: %d, want: %d", test.query, got, want)
+		}
+	}
+	if got, want := len(usr.Transactions()), 1; got != want {
+		t.Errorf("transactions: got: %d, want: %d", got, want)
+	}
+}
+
+func TestUploadFormats(t *testing.T) {
+	m, err := session.CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+
+	uploadHdl := NewUploadHandler(m)
+	srv := httptest.NewServer(uploadHdl)
+	defer srv.Close()
+
+	client := srv.Client()
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		t.Fatalf("cookiejar.New(): %v", err)
+	}
+	client.Jar = jar
+	urlStr := fmt.Sprintf("%s/upload", srv.URL)
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		t.Fatalf("url.Parse(%s): %v", urlStr, err)
+	}
+
+	// Login.
+	usr, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("testManager.Login: %v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{&http.Cookie{Name: sessCookieKey, Value: token}})
+
+	const mt940 = ":20:STARTUMS\n:25:37040044/0532013000\n:61:1805010501DR12,50NDDTNONREF//BANKREF1\n:86:Strom Mai\n-\n"
+	tests := []struct {
+		query    string
+		body     string
+		wantCode int
+	}{
+		{
+			query:    "?format=mt940",
+			body:     mt940,
+			wantCode: http.StatusOK,
+		},
+		{
+			query:    "?format=camt053",
+			body:     "<Document></Document>",
+			wantCode: http.StatusOK,
+		},
+		{
+			query:    "?format=qif",
+			body:     mt940,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			query:    "?decimal=x",
+			body:     "Date,No.,Description,Debit,Credit\n",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		resp, err := client.Post(urlStr+test.query, "text/plain", strings.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("client.Post(%s): %v", urlStr, err)
+		}
+		resp.Body.Close()
+		if got, want := resp.StatusCode, test.wantCode; got != want {
+			t.Errorf("POST %s: got: %d, want: %d", test.query, got, want)
+		}
+	}
+	if got, want := usr.Transactions()[0].ID, "REF-37040044/0532013000-BANKREF1"; got != want {
+		t.Errorf("transaction ID: got: %s, want: %s", got, want)
+	}
+}
@@ -5,6 +5,10 @@ module.controller('loginController', ['$scope', '$http', '$rootScope', '$locatio
 	
 	$scope.login = function(user) {
 		$http.post('/session', angular.toJson(user)).success(function(resp) {
+			if (resp.recoveryCode) {
+				window.alert("Your account now has a recovery code: " + resp.recoveryCode + "\n\n" +
+					"Write it down and keep it safe. It is the only way back into your account if you forget your password, and it will not be shown again.");
+			}
 			$.notify("Welcome, " + resp.name, "success");
 			$rootScope.user = resp;
 			$location.url('/overview');
@@ -6,6 +6,8 @@ module.controller('registerController', ['$rootScope', '$scope', '$http', '$loca
   
   $scope.register = function(user) {
     $http.put('/session', angular.toJson(user)).success(function(resp) {
+      window.alert("Your recovery code is " + resp.recoveryCode + "\n\n" +
+        "Write it down and keep it safe. It is the only way back into your account if you forget your password, and it will not be shown again.");
       $.notify("Welcome, " + resp.name, "success");
       $rootScope.user = resp;
       $location.url('/overview');
@@ -7,16 +7,27 @@ module.controller('transactionController', ['$scope', '$http', function($scope,
   $scope.upload = function() {
     var f = document.getElementById('file').files[0];
     var r = new FileReader();
+    var format = "csv";
+    if (/\.xml$/i.test(f.name)) {
+      format = "camt053";
+    } else if (/\.(sta|940|mt940)$/i.test(f.name)) {
+      format = "mt940";
+    }
 
     r.onloadend = function(e) {
       $http({
-        url: "/upload",
+        url: "/upload?format=" + format + "&filename=" + encodeURIComponent(f.name),
         method: "POST",
         data: e.target.result,
       }).success(function (resp) {
         $.notify("Uploaded " + resp.uploaded + " transactions, imported " + resp.imported + " new transactions", "success");
         $scope.update();
       }).error(function (response) {
+        if (response.errors) {
+          var e = response.errors[0];
+          $.notify(response.errors.length + " rows could not be read, no data was imported. Row " + e.row + ": " + e.reason, "error");
+          return;
+        }
         $.notify(response, "error");
       });
     }
@@ -0,0 +1,162 @@
+// Package payee turns raw bank transaction descriptions into clean merchant names.
+package payee
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	// Card processors and point-of-sale boilerplate that banks put in front of the merchant.
+	prefixRE = regexp.MustCompile(`^(?:(?:POS|DEBIT CARD|DEBIT|CHECKCARD|CHECK CARD|VISA|MC|ACH|RECURRING|ONLINE|PREAUTHORIZED)\s+)*(?:PURCHASE AUTHORIZED ON|PURCHASE|PAYMENT|DEBIT|WITHDRAWAL)?\s*`)
+	// Payment processors that prefix the merchant, like "SQ *BLUE BOTTLE".
+	processorRE = regexp.MustCompile(`^(?:SQ|SQU|SP|TST|PY|PP|PAYPAL|GOOGLE|AMZN MKTP|IZ|WPY)\s*\*\s*`)
+	dateRE      = regexp.MustCompile(`\b\d{1,4}[/.-]\d{1,2}(?:[/.-]\d{2,4})?\b`)
+	cardRE      = regexp.MustCompile(`(?:\b(?:CARD|ACCT|XX+|\*+)\s*#?\s*\d{2,}\b|[X*]{4,}\d*)`)
+	storeRE     = regexp.MustCompile(`(?:#\s*\d+|\b(?:STORE|STR|NO\.?)\s*#?\d+)\b`)
+	numberRE    = regexp.MustCompile(`\b[A-Z]*\d[\dA-Z]*\b`)
+	domainRE    = regexp.MustCompile(`\.(?:COM|NET|ORG|CO)\b`)
+	suffixRE    = regexp.MustCompile(`\s+(?:WHSE|WHOLESALE|INC|LLC|LTD|CORP|CO)$`)
+
+	states = map[string]bool{
+		"AL": true, "AK": true, "AZ": true, "AR": true, "CA": true, "CO": true, "CT": true, "DE": true,
+		"DC": true, "FL": true, "GA": true, "HI": true, "ID": true, "IL": true, "IN": true, "IA": true,
+		"KS": true, "KY": true, "LA": true, "ME": true, "MD": true, "MA": true, "MI": true, "MN": true,
+		"MS": true, "MO": true, "MT": true, "NE": true, "NV": true, "NH": true, "NJ": true, "NM": true,
+		"NY": true, "NC": true, "ND": true, "OH": true, "OK": true, "OR": true, "PA": true, "RI": true,
+		"SC": true, "SD": true, "TN": true, "TX": true, "UT": true, "VT": true, "VA": true, "WA": true,
+		"WV": true, "WI": true, "WY": true,
+	}
+)
+
+func titleCase(s string) string {
+	words := strings.Fields(strings.ToLower(s))
+	for i, w := range words {
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, " ")
+}
+
+// dropLocation removes a trailing "CITY ST" location, keeping at least one word of the name.
+func dropLocation(s string) string {
+	words := strings.Fields(s)
+	switch l := len(words); {
+	case l >= 3 && states[words[l-1]]:
+		words = words[:l-2]
+	case l == 2 && states[words[l-1]]:
+		words = words[:l-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// Normalize cleans a raw bank description with built-in heuristics: processor prefixes, card
+// numbers, store numbers, dates and trailing locations are removed, and the rest is title cased.
+// The raw description is returned trimmed if nothing would be left.
+func Normalize(desc string) string {
+	s := strings.ToUpper(strings.TrimSpace(desc))
+	s = prefixRE.ReplaceAllString(s, "")
+	s = processorRE.ReplaceAllString(s, "")
+	s = dateRE.ReplaceAllString(s, " ")
+	s = cardRE.ReplaceAllString(s, " ")
+	s = storeRE.ReplaceAllString(s, " ")
+	s = domainRE.ReplaceAllString(s, " ")
+	s = numberRE.ReplaceAllString(s, " ")
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || r == '&' || r == '\'' {
+			return r
+		}
+		return ' '
+	}, s)
+	s = dropLocation(s)
+	for {
+		trimmed := suffixRE.ReplaceAllString(s, "")
+		if trimmed == s {
+			break
+		}
+		s = trimmed
+	}
+	if s = titleCase(s); s == "" {
+		return strings.TrimSpace(desc)
+	}
+	return s
+}
+
+// Alias maps every description containing Match, ignoring case, to the payee Name.
+type Alias struct {
+	Match string `json:"match"`
+	Name  string `json:"name"`
+}
+
+// Directory is a user-editable table of Aliases applied before the built-in heuristics.
+type Directory struct {
+	aliases []*Alias
+}
+
+// NewDirectory returns a Directory with the given Aliases.
+func NewDirectory(aliases []*Alias) *Directory {
+	d := &Directory{}
+	for _, a := range aliases {
+		d.Set(a)
+	}
+	return d
+}
+
+// Aliases returns this Directory's Aliases, sorted by Match.
+func (d *Directory) Aliases() []*Alias {
+	if d == nil {
+		return nil
+	}
+	return d.aliases
+}
+
+// Set adds an Alias, replacing any Alias with the same Match.
+func (d *Directory) Set(a *Alias) error {
+	a.Match = strings.TrimSpace(a.Match)
+	a.Name = strings.TrimSpace(a.Name)
+	if a.Match == "" || a.Name == "" {
+		return fmt.Errorf("alias match and name must not be empty")
+	}
+	for i, old := range d.aliases {
+		if strings.EqualFold(old.Match, a.Match) {
+			d.aliases[i] = a
+			return nil
+		}
+	}
+	d.aliases = append(d.aliases, a)
+	sort.Slice(d.aliases, func(i, j int) bool {
+		return d.aliases[i].Match < d.aliases[j].Match
+	})
+	return nil
+}
+
+// Delete removes the Alias with the given Match, returning true if it existed.
+func (d *Directory) Delete(match string) bool {
+	for i, a := range d.aliases {
+		if strings.EqualFold(a.Match, match) {
+			d.aliases = append(d.aliases[:i], d.aliases[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Name returns the payee of a raw description. The longest matching Alias wins, and the built-in
+// heuristics are used when no Alias matches.
+func (d *Directory) Name(desc string) string {
+	var best *Alias
+	upper := strings.ToUpper(desc)
+	for _, a := range d.Aliases() {
+		if strings.Contains(upper, strings.ToUpper(a.Match)) && (best == nil || len(a.Match) > len(best.Match)) {
+			best = a
+		}
+	}
+	if best != nil {
+		return best.Name
+	}
+	return Normalize(desc)
+}
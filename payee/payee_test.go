@@ -0,0 +1,64 @@
+package payee
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		desc string
+		want string
+	}{
+		{desc: "POS PURCHASE 0423 COSTCO WHSE #1234 SEATTLE WA", want: "Costco"},
+		{desc: "SQ *BLUE BOTTLE COFFEE", want: "Blue Bottle Coffee"},
+		{desc: "PURCHASE AUTHORIZED ON 04/21 SAFEWAY STORE 1234 CARD 5678", want: "Safeway"},
+		{desc: "NETFLIX.COM", want: "Netflix"},
+		{desc: "Trader Joe's", want: "Trader Joe's"},
+		{desc: "12345", want: "12345"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got, want := Normalize(test.desc), test.want; got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestDirectoryName(t *testing.T) {
+	d := NewDirectory([]*Alias{
+		{Match: "amzn", Name: "Amazon"},
+		{Match: "AMZN PRIME", Name: "Amazon Prime"},
+	})
+	tests := []struct {
+		desc string
+		want string
+	}{
+		{desc: "AMZN MKTP US*2K4", want: "Amazon"},
+		{desc: "AMZN PRIME MEMBERSHIP", want: "Amazon Prime"},
+		{desc: "SQ *BLUE BOTTLE COFFEE", want: "Blue Bottle Coffee"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got, want := d.Name(test.desc), test.want; got != want {
+				t.Errorf("got: %q, want: %q", got, want)
+			}
+		})
+	}
+
+	if err := d.Set(&Alias{Match: "Amzn", Name: "Amazon.com"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got, want := len(d.Aliases()), 2; got != want {
+		t.Errorf("aliases after replace: got: %d, want: %d", got, want)
+	}
+	if err := d.Set(&Alias{Match: " ", Name: "Nothing"}); err == nil {
+		t.Errorf("Set with empty match: got: nil error, want: error")
+	}
+	if got, want := d.Delete("AMZN PRIME"), true; got != want {
+		t.Errorf("Delete: got: %t, want: %t", got, want)
+	}
+	if got, want := d.Name("AMZN PRIME MEMBERSHIP"), "Amazon.com"; got != want {
+		t.Errorf("Name after delete: got: %q, want: %q", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+package recurring
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	icalDate     = "20060102"
+	icalDateTime = "20060102T150405Z"
+	icalLineLen  = 75
+)
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", "")
+
+// foldLine splits a content line into lines of at most 75 octets, as RFC 5545 section 3.1
+// requires, without splitting a UTF-8 sequence.
+func foldLine(line string) string {
+	var b strings.Builder
+	limit := icalLineLen
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts against their length.
+		limit = icalLineLen - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// WriteICS writes the given Occurrences as an RFC 5545 iCalendar of all-day events.
+func WriteICS(w io.Writer, name string, occs []*Occurrence, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(format string, args ...interface{}) {
+		bw.WriteString(foldLine(fmt.Sprintf(format, args...)))
+	}
+	stamp := now.UTC().Format(icalDateTime)
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Oyster//Upcoming Bills//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:%s", icalEscaper.Replace(name))
+	for _, o := range occs {
+		line("BEGIN:VEVENT")
+		line("UID:%s@oyster", o.UID)
+		line("DTSTAMP:%s", stamp)
+		line("DTSTART;VALUE=DATE:%s", o.Date.Format(icalDate))
+		line("DTEND;VALUE=DATE:%s", o.Date.AddDate(0, 0, 1).Format(icalDate))
+		line("SUMMARY:%s", icalEscaper.Replace(fmt.Sprintf("%s ($%.2f)", o.Description, o.Amount)))
+		desc := fmt.Sprintf("%s transaction of $%.2f", o.Source, o.Amount)
+		if o.Account != "" {
+			desc += fmt.Sprintf(" from %s", o.Account)
+		}
+		line("DESCRIPTION:%s", icalEscaper.Replace(desc))
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
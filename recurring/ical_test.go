@@ -0,0 +1,43 @@
+package recurring
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFoldLine(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("é", 60)
+	folded := foldLine(line)
+	for _, l := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+		if len(l) > icalLineLen {
+			t.Errorf("line too long: %d octets", len(l))
+		}
+	}
+	if got, want := strings.Replace(folded, "\r\n ", "", -1), line+"\r\n"; got != want {
+		t.Errorf("unfolded: got: %q, want: %q", got, want)
+	}
+}
+
+func TestWriteICS(t *testing.T) {
+	d := day(2018, time.June, 12)
+	occs := []*Occurrence{occurrence(Scheduled, "SCHED-1", "Rent; apt, 4", "checking", "Housing", -1000, d)}
+	buf := &bytes.Buffer{}
+	if err := WriteICS(buf, "test", occs, d); err != nil {
+		t.Fatalf("WriteICS: %v", err)
+	}
+	ics := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"VERSION:2.0\r\n",
+		"DTSTART;VALUE=DATE:20180612\r\n",
+		"DTEND;VALUE=DATE:20180613\r\n",
+		`SUMMARY:Rent\; apt\, 4 ($-1000.00)` + "\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("missing %q in:\n%s", want, ics)
+		}
+	}
+}
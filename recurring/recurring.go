@@ -0,0 +1,261 @@
+// Package recurring finds series of regular transactions, such as subscriptions and bills, in a
+// user's history.
+package recurring
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/groggygopher/oyster/register"
+)
+
+// Cadence is how often the transactions of a Series occur.
+type Cadence string
+
+// The cadences that Analyze can detect. Once is only used by a Schedule that does not repeat.
+const (
+	Once    Cadence = "once"
+	Weekly  Cadence = "weekly"
+	Monthly Cadence = "monthly"
+	Annual  Cadence = "annual"
+)
+
+// Add returns the date n periods of this Cadence after t. Monthly and annual dates past the end of
+// a shorter month fall on its last day, as January 31st is followed by February 28th, so a series
+// keeps its day of the month when every date is counted from the same t.
+func (c Cadence) Add(t time.Time, n int) time.Time {
+	switch c {
+	case Weekly:
+		return t.AddDate(0, 0, 7*n)
+	case Monthly:
+		return addMonths(t, n)
+	case Annual:
+		return addMonths(t, 12*n)
+	}
+	return t
+}
+
+// addMonths returns the date n months after t, clamped to the last day of that month instead of
+// overflowing into the next one as time.AddDate does.
+func addMonths(t time.Time, n int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	return first.AddDate(0, 0, d-1)
+}
+
+// tolerance is how far, in days, an occurrence may drift from its expected date.
+func (c Cadence) tolerance() float64 {
+	switch c {
+	case Weekly:
+		return 2
+	case Monthly:
+		return 5
+	case Annual:
+		return 20
+	}
+	return 0
+}
+
+// stalePeriods is how many occurrences in a row may go missing after the last transaction before
+// a series is taken to have ended, as when a subscription is cancelled.
+const stalePeriods = 2
+
+func (c Cadence) minOccurrences() int {
+	if c == Annual {
+		return 2
+	}
+	return 3
+}
+
+// PriceChange records an occurrence of a Series whose amount differed from the one before it.
+type PriceChange struct {
+	Date *time.Time `json:"date"`
+	From float64    `json:"from"`
+	To   float64    `json:"to"`
+}
+
+// Series is a run of similar transactions that recur at a regular Cadence. Its Category is the one
+// its latest transaction is filed under, if that transaction is not split.
+type Series struct {
+	Description  string         `json:"description"`
+	Account      string         `json:"account"`
+	Category     string         `json:"category"`
+	Cadence      Cadence        `json:"cadence"`
+	Amount       float64        `json:"amount"`
+	Last         *time.Time     `json:"last"`
+	Next         *time.Time     `json:"next"`
+	Transactions []string       `json:"transactions"`
+	Missed       []*time.Time   `json:"missed"`
+	PriceChanges []*PriceChange `json:"priceChanges"`
+}
+
+// Key reduces a transaction description to the part that stays the same from one occurrence to the
+// next, by dropping digits and punctuation.
+func Key(desc string) string {
+	fields := strings.FieldsFunc(strings.ToLower(desc), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+func days(a, b time.Time) float64 {
+	return b.Sub(a).Hours() / 24
+}
+
+func median(fs []float64) float64 {
+	sorted := append([]float64(nil), fs...)
+	sort.Float64s(sorted)
+	l := len(sorted)
+	if l%2 == 1 {
+		return sorted[l/2]
+	}
+	return (sorted[l/2-1] + sorted[l/2]) / 2
+}
+
+func detectCadence(dates []time.Time) (Cadence, bool) {
+	var intervals []float64
+	for i := 1; i < len(dates); i++ {
+		intervals = append(intervals, days(dates[i-1], dates[i]))
+	}
+	m := median(intervals)
+	switch {
+	case m >= 5 && m <= 9:
+		return Weekly, true
+	case m >= 25 && m <= 36:
+		return Monthly, true
+	case m >= 340 && m <= 390:
+		return Annual, true
+	}
+	return "", false
+}
+
+// periods returns how many periods of the Cadence separate a and b, and false if b does not fall
+// near any expected date after a.
+func periods(c Cadence, a, b time.Time) (int, bool) {
+	for n := 1; ; n++ {
+		exp := c.Add(a, n)
+		if math.Abs(days(exp, b)) <= c.tolerance() {
+			return n, true
+		}
+		if days(exp, b) < -c.tolerance() {
+			return 0, false
+		}
+	}
+}
+
+func similarAmounts(amounts []float64) bool {
+	m := math.Abs(median(amounts))
+	var similar int
+	for _, a := range amounts {
+		if math.Abs(math.Abs(a)-m) <= 0.25*m {
+			similar++
+		}
+	}
+	return similar*2 > len(amounts)
+}
+
+func analyzeGroup(trans []*register.Transaction, now time.Time) *Series {
+	sort.SliceStable(trans, func(i, j int) bool {
+		return trans[i].Date.Before(*trans[j].Date)
+	})
+	var dates []time.Time
+	var amounts []float64
+	for _, t := range trans {
+		dates = append(dates, *t.Date)
+		amounts = append(amounts, t.Amount)
+	}
+	c, ok := detectCadence(dates)
+	if !ok || len(trans) < c.minOccurrences() || !similarAmounts(amounts) {
+		return nil
+	}
+
+	s := &Series{
+		Description: trans[len(trans)-1].Description,
+		Account:     trans[len(trans)-1].Account,
+		Cadence:     c,
+		Amount:      median(amounts),
+	}
+	if cats := trans[len(trans)-1].Category; len(cats) == 1 {
+		s.Category = cats[0].Name
+	}
+	var regular int
+	for i, t := range trans {
+		s.Transactions = append(s.Transactions, t.ID)
+		if i == 0 {
+			continue
+		}
+		prev := trans[i-1]
+		n, ok := periods(c, *prev.Date, *t.Date)
+		if ok {
+			regular++
+			for k := 1; k < n; k++ {
+				missed := c.Add(*prev.Date, k)
+				s.Missed = append(s.Missed, &missed)
+			}
+		}
+		if math.Round(prev.Amount*100) != math.Round(t.Amount*100) {
+			s.PriceChanges = append(s.PriceChanges, &PriceChange{Date: t.Date, From: prev.Amount, To: t.Amount})
+		}
+	}
+	// Most of the gaps must land on the cadence for this to be a real series.
+	if float64(regular) < 0.75*float64(len(trans)-1) {
+		return nil
+	}
+
+	last := dates[len(dates)-1]
+	s.Last = &last
+	limit := len(s.Missed) + stalePeriods
+	k := 1
+	next := c.Add(last, k)
+	for days(next, now) > c.tolerance() {
+		if len(s.Missed) == limit {
+			return nil
+		}
+		missed := next
+		s.Missed = append(s.Missed, &missed)
+		k++
+		next = c.Add(last, k)
+	}
+	s.Next = &next
+	return s
+}
+
+// Analyze scans the given transactions for series with similar payees, similar amounts and
+// regular weekly, monthly or annual intervals. Occurrences that never arrived, up to now, are
+// reported as missed. Series whose last transaction was more than stalePeriods occurrences ago have
+// ended and are left out. Linked transfers are ignored.
+func Analyze(trans []*register.Transaction, now time.Time) []*Series {
+	groups := make(map[string][]*register.Transaction)
+	var keys []string
+	for _, t := range trans {
+		if t.Date == nil || t.Amount == 0 || t.IsTransfer() {
+			continue
+		}
+		k := Key(t.Description)
+		if t.Payee != "" {
+			k = Key(t.Payee)
+		}
+		if t.Amount > 0 {
+			k = "+" + k
+		}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], t)
+	}
+	sort.Strings(keys)
+
+	var res []*Series
+	for _, k := range keys {
+		if s := analyzeGroup(groups[k], now); s != nil {
+			res = append(res, s)
+		}
+	}
+	return res
+}
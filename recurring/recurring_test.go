@@ -0,0 +1,178 @@
+package recurring
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/groggygopher/oyster/register"
+)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func series(desc string, amounts []float64, dates ...time.Time) []*register.Transaction {
+	var trans []*register.Transaction
+	for i, d := range dates {
+		d := d
+		trans = append(trans, &register.Transaction{
+			ID:          fmt.Sprintf("%s-%d", desc, i),
+			Description: fmt.Sprintf("%s #%d", desc, 1000+i),
+			Amount:      amounts[i%len(amounts)],
+			Date:        &d,
+		})
+	}
+	return trans
+}
+
+func TestKey(t *testing.T) {
+	if got, want := Key("NETFLIX.COM 866-579-7172 CA"), "netflix com ca"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestCadenceAdd(t *testing.T) {
+	tests := []struct {
+		cadence Cadence
+		t       time.Time
+		n       int
+		want    time.Time
+	}{
+		{cadence: Weekly, t: day(2018, time.January, 31), n: 1, want: day(2018, time.February, 7)},
+		{cadence: Monthly, t: day(2018, time.January, 15), n: 1, want: day(2018, time.February, 15)},
+		{cadence: Monthly, t: day(2018, time.January, 31), n: 1, want: day(2018, time.February, 28)},
+		{cadence: Monthly, t: day(2018, time.January, 31), n: 2, want: day(2018, time.March, 31)},
+		{cadence: Monthly, t: day(2018, time.January, 31), n: 3, want: day(2018, time.April, 30)},
+		{cadence: Monthly, t: day(2018, time.March, 31), n: -1, want: day(2018, time.February, 28)},
+		{cadence: Monthly, t: day(2019, time.December, 30), n: 2, want: day(2020, time.February, 29)},
+		{cadence: Annual, t: day(2016, time.February, 29), n: 1, want: day(2017, time.February, 28)},
+		{cadence: Annual, t: day(2016, time.February, 29), n: 4, want: day(2020, time.February, 29)},
+		{cadence: Once, t: day(2018, time.January, 31), n: 1, want: day(2018, time.January, 31)},
+	}
+	for _, test := range tests {
+		if got, want := test.cadence.Add(test.t, test.n), test.want; !got.Equal(want) {
+			t.Errorf("%s.Add(%v, %d): got: %v, want: %v", test.cadence, test.t, test.n, got, want)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	now := day(2018, time.June, 10)
+	tests := []struct {
+		label        string
+		trans        []*register.Transaction
+		wantCadence  Cadence
+		wantNext     time.Time
+		wantMissed   int
+		wantChanges  int
+		wantNoSeries bool
+		wantAmount   float64
+	}{
+		{
+			label:       "monthly subscription",
+			trans:       series("NETFLIX", []float64{-10.99}, day(2018, time.March, 5), day(2018, time.April, 5), day(2018, time.May, 6)),
+			wantCadence: Monthly,
+			wantNext:    day(2018, time.June, 6),
+			wantAmount:  -10.99,
+		},
+		{
+			label:       "weekly with a missed week",
+			trans:       series("GYM", []float64{-5}, day(2018, time.May, 6), day(2018, time.May, 13), day(2018, time.May, 27), day(2018, time.June, 3)),
+			wantCadence: Weekly,
+			wantNext:    day(2018, time.June, 10),
+			wantMissed:  1,
+			wantAmount:  -5,
+		},
+		{
+			label:       "price change",
+			trans:       series("SPOTIFY", []float64{-9.99, -9.99, -12.99}, day(2018, time.March, 5), day(2018, time.April, 5), day(2018, time.May, 5)),
+			wantCadence: Monthly,
+			wantNext:    day(2018, time.June, 5),
+			wantChanges: 1,
+			wantAmount:  -9.99,
+		},
+		{
+			label:       "stopped arriving",
+			trans:       series("MAGAZINE", []float64{-3}, day(2018, time.January, 15), day(2018, time.February, 15), day(2018, time.March, 15)),
+			wantCadence: Monthly,
+			wantNext:    day(2018, time.June, 15),
+			wantMissed:  2,
+			wantAmount:  -3,
+		},
+		{
+			label:       "end of the month",
+			trans:       series("RENT", []float64{-900}, day(2018, time.February, 28), day(2018, time.March, 31), day(2018, time.April, 30), day(2018, time.May, 31)),
+			wantCadence: Monthly,
+			wantNext:    day(2018, time.June, 30),
+			wantAmount:  -900,
+		},
+		{
+			label:        "cancelled",
+			trans:        series("NEWSPAPER", []float64{-8}, day(2017, time.November, 20), day(2017, time.December, 20), day(2018, time.January, 20), day(2018, time.February, 20)),
+			wantNoSeries: true,
+		},
+		{
+			label:        "lapsed years ago",
+			trans:        series("LAUNDRY", []float64{-6}, day(2015, time.March, 1), day(2015, time.March, 8), day(2015, time.March, 15)),
+			wantNoSeries: true,
+		},
+		{
+			label:        "irregular",
+			trans:        series("COFFEE", []float64{-4}, day(2018, time.May, 1), day(2018, time.May, 3), day(2018, time.May, 20), day(2018, time.June, 9)),
+			wantNoSeries: true,
+		},
+		{
+			label:        "dissimilar amounts",
+			trans:        series("GROCERY", []float64{-20, -150, -75}, day(2018, time.March, 1), day(2018, time.April, 1), day(2018, time.May, 1)),
+			wantNoSeries: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			got := Analyze(test.trans, now)
+			if test.wantNoSeries {
+				if len(got) != 0 {
+					t.Errorf("expected no series, got: %v", got[0])
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("series: got: %d, want: 1", len(got))
+			}
+			s := got[0]
+			if got, want := s.Cadence, test.wantCadence; got != want {
+				t.Errorf("cadence: got: %s, want: %s", got, want)
+			}
+			if got, want := *s.Next, test.wantNext; !got.Equal(want) {
+				t.Errorf("next: got: %v, want: %v", got, want)
+			}
+			if got, want := len(s.Missed), test.wantMissed; got != want {
+				t.Errorf("missed: got: %v, want: %d", s.Missed, want)
+			}
+			if got, want := len(s.PriceChanges), test.wantChanges; got != want {
+				t.Errorf("price changes: got: %d, want: %d", got, want)
+			}
+			if got, want := s.Amount, test.wantAmount; got != want {
+				t.Errorf("amount: got: %f, want: %f", got, want)
+			}
+			if got, want := len(s.Transactions), len(test.trans); got != want {
+				t.Errorf("transactions: got: %d, want: %d", got, want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeAnnual(t *testing.T) {
+	trans := series("DOMAIN RENEWAL", []float64{-15}, day(2016, time.July, 1), day(2017, time.July, 2))
+	got := Analyze(trans, day(2018, time.June, 1))
+	if len(got) != 1 {
+		t.Fatalf("series: got: %d, want: 1", len(got))
+	}
+	want := day(2018, time.July, 2)
+	if got := got[0]; got.Cadence != Annual || !reflect.DeepEqual(*got.Next, want) {
+		t.Errorf("got: %s next %v, want: %s next %v", got.Cadence, got.Next, Annual, want)
+	}
+}
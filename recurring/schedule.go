@@ -0,0 +1,121 @@
+package recurring
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Schedule is a user-defined transaction that is expected on a date, and optionally repeats at a
+// Cadence after that. Category is optional, and names the category it will be filed under.
+type Schedule struct {
+	ID          string     `json:"id"`
+	Description string     `json:"description"`
+	Account     string     `json:"account"`
+	Category    string     `json:"category"`
+	Amount      float64    `json:"amount"`
+	Cadence     Cadence    `json:"cadence"`
+	Start       *time.Time `json:"start"`
+}
+
+// Validate returns an error if this Schedule cannot produce any Occurrences.
+func (s *Schedule) Validate() error {
+	if s.Description == "" {
+		return fmt.Errorf("schedule description must not be empty")
+	}
+	if s.Start == nil {
+		return fmt.Errorf("schedule %s must have a start date", s.Description)
+	}
+	switch s.Cadence {
+	case Once, Weekly, Monthly, Annual:
+		return nil
+	}
+	return fmt.Errorf("unknown cadence: %q", s.Cadence)
+}
+
+// Source is where an Occurrence was predicted from.
+type Source string
+
+// The Sources of an Occurrence.
+const (
+	Predicted Source = "predicted"
+	Scheduled Source = "scheduled"
+)
+
+// Occurrence is a single upcoming transaction.
+type Occurrence struct {
+	UID         string     `json:"uid"`
+	Date        *time.Time `json:"date"`
+	Description string     `json:"description"`
+	Account     string     `json:"account"`
+	Category    string     `json:"category"`
+	Amount      float64    `json:"amount"`
+	Source      Source     `json:"source"`
+}
+
+// occurrence returns an Occurrence on date of the Schedule with the given ID, or of a Series when
+// id is empty. Its UID stays the same from one calendar feed to the next.
+func occurrence(src Source, id, desc, account, category string, amount float64, date time.Time) *Occurrence {
+	uid := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s", src, id, desc, date.Format("2006-01-02"))))
+	return &Occurrence{
+		UID:         hex.EncodeToString(uid[:16]),
+		Date:        &date,
+		Description: desc,
+		Account:     account,
+		Category:    category,
+		Amount:      amount,
+		Source:      src,
+	}
+}
+
+// Upcoming returns every predicted occurrence of the given Series, and every occurrence of the
+// given Schedules, from the start of the given day through the following number of days, sorted by
+// date.
+func Upcoming(series []*Series, schedules []*Schedule, from time.Time, days int) []*Occurrence {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	until := from.AddDate(0, 0, days)
+	var occs []*Occurrence
+	for _, s := range series {
+		if s.Next == nil {
+			continue
+		}
+		// Count from the last transaction, so that a series on the 31st stays there after a
+		// shorter month.
+		anchor, n := *s.Next, 0
+		if s.Last != nil {
+			anchor, n = *s.Last, 1
+		}
+		for ; ; n++ {
+			d := s.Cadence.Add(anchor, n)
+			if !d.Before(until) {
+				break
+			}
+			if !d.Before(from) && !d.Before(*s.Next) {
+				occs = append(occs, occurrence(Predicted, "", s.Description, s.Account, s.Category, s.Amount, d))
+			}
+		}
+	}
+	for _, s := range schedules {
+		if s.Start == nil {
+			continue
+		}
+		for n := 0; ; n++ {
+			d := s.Cadence.Add(*s.Start, n)
+			if !d.Before(until) {
+				break
+			}
+			if !d.Before(from) {
+				occs = append(occs, occurrence(Scheduled, s.ID, s.Description, s.Account, s.Category, s.Amount, d))
+			}
+			if s.Cadence == Once {
+				break
+			}
+		}
+	}
+	sort.SliceStable(occs, func(i, j int) bool {
+		return occs[i].Date.Before(*occs[j].Date)
+	})
+	return occs
+}
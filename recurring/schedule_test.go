@@ -0,0 +1,105 @@
+package recurring
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScheduleValidate(t *testing.T) {
+	start := day(2018, time.June, 1)
+	tests := []struct {
+		label    string
+		schedule *Schedule
+		wantErr  bool
+	}{
+		{
+			label:    "valid",
+			schedule: &Schedule{Description: "rent", Cadence: Monthly, Start: &start},
+		},
+		{
+			label:    "no description",
+			schedule: &Schedule{Cadence: Monthly, Start: &start},
+			wantErr:  true,
+		},
+		{
+			label:    "no start",
+			schedule: &Schedule{Description: "rent", Cadence: Monthly},
+			wantErr:  true,
+		},
+		{
+			label:    "unknown cadence",
+			schedule: &Schedule{Description: "rent", Cadence: "daily", Start: &start},
+			wantErr:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			err := test.schedule.Validate()
+			if got, want := err != nil, test.wantErr; got != want {
+				t.Errorf("wantErr: err: %v, got: %t, want: %t", err, got, want)
+			}
+		})
+	}
+}
+
+func TestUpcoming(t *testing.T) {
+	var (
+		next     = day(2018, time.June, 12)
+		rent     = day(2018, time.January, 1)
+		taxes    = day(2018, time.June, 20)
+		pastOnce = day(2018, time.May, 20)
+	)
+	series := []*Series{{Description: "GYM", Cadence: Weekly, Amount: -5, Next: &next}}
+	schedules := []*Schedule{
+		{Description: "rent", Cadence: Monthly, Amount: -1000, Start: &rent},
+		{Description: "taxes", Cadence: Once, Amount: -300, Start: &taxes},
+		{Description: "past", Cadence: Once, Amount: -1, Start: &pastOnce},
+	}
+
+	occs := Upcoming(series, schedules, time.Date(2018, time.June, 10, 15, 0, 0, 0, time.UTC), 30)
+	var got []string
+	for _, o := range occs {
+		got = append(got, o.Date.Format("01/02")+" "+o.Description)
+	}
+	want := []string{"06/12 GYM", "06/19 GYM", "06/20 taxes", "06/26 GYM", "07/01 rent", "07/03 GYM"}
+	if len(got) != len(want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("occurrence %d: got: %s, want: %s", i, got[i], want[i])
+		}
+	}
+	if occs[0].Source != Predicted || occs[2].Source != Scheduled {
+		t.Errorf("sources: got: %s, %s, want: %s, %s", occs[0].Source, occs[2].Source, Predicted, Scheduled)
+	}
+}
+
+func TestUpcomingUIDs(t *testing.T) {
+	start := day(2018, time.June, 12)
+	schedules := []*Schedule{
+		{ID: "SCHED-1", Description: "rent", Cadence: Once, Amount: -500, Start: &start},
+		{ID: "SCHED-2", Description: "rent", Cadence: Once, Amount: -500, Start: &start},
+	}
+	occs := Upcoming(nil, schedules, day(2018, time.June, 10), 30)
+	if len(occs) != 2 {
+		t.Fatalf("occurrences: got: %d, want: 2", len(occs))
+	}
+	if occs[0].UID == occs[1].UID {
+		t.Errorf("schedules with the same description and date should have distinct UIDs: %s", occs[0].UID)
+	}
+}
+
+func TestUpcomingMonthEnd(t *testing.T) {
+	last, next := day(2018, time.May, 31), day(2018, time.June, 30)
+	series := []*Series{{Description: "RENT", Cadence: Monthly, Amount: -900, Last: &last, Next: &next}}
+	occs := Upcoming(series, nil, day(2018, time.June, 1), 70)
+	var got []string
+	for _, o := range occs {
+		got = append(got, o.Date.Format("01/02"))
+	}
+	if want := []string{"06/30", "07/31"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,38 @@
+package register
+
+import (
+	"fmt"
+	"time"
+)
+
+// Account is a bank account with a known balance on a date. Threshold is the balance the user
+// wants to stay above.
+type Account struct {
+	Name      string     `json:"name"`
+	Balance   float64    `json:"balance"`
+	AsOf      *time.Time `json:"asOf"`
+	Threshold float64    `json:"threshold"`
+}
+
+// Validate returns an error if this Account is missing its name or balance date.
+func (a *Account) Validate() error {
+	if a.Name == "" {
+		return fmt.Errorf("account name must not be empty")
+	}
+	if a.AsOf == nil {
+		return fmt.Errorf("account %s must have a balance date", a.Name)
+	}
+	return nil
+}
+
+// CurrentBalance returns the Account's balance updated with every given Transaction of the Account
+// dated after the balance was taken.
+func (a *Account) CurrentBalance(trans []*Transaction) float64 {
+	bal := a.Balance
+	for _, t := range trans {
+		if t.Account == a.Name && t.Date != nil && t.Date.After(*a.AsOf) {
+			bal += t.Amount
+		}
+	}
+	return bal
+}
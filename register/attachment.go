@@ -0,0 +1,38 @@
+package register
+
+import "time"
+
+// Attachment is a reference to a file, such as a scanned receipt, attached to a Transaction. The
+// file contents are stored separately from the Transaction.
+type Attachment struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	ContentType string     `json:"contentType"`
+	Size        int        `json:"size"`
+	Added       *time.Time `json:"added"`
+}
+
+// Attachment returns the Attachment of this Transaction with the given ID, or nil if there is none.
+func (t *Transaction) Attachment(id string) *Attachment {
+	for _, a := range t.Attachments {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+// RemoveAttachment removes the Attachment with the given ID from this Transaction, returning true
+// if it was found.
+func (t *Transaction) RemoveAttachment(id string) bool {
+	for i, a := range t.Attachments {
+		if a.ID == id {
+			t.Attachments = append(t.Attachments[:i], t.Attachments[i+1:]...)
+			if len(t.Attachments) == 0 {
+				t.Attachments = nil
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package register
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -36,6 +37,18 @@ func (b *Budget) Name() string {
 	return b.name
 }
 
+// Rename moves this Budget to the category with the given name.
+func (b *Budget) Rename(name string) {
+	b.name = name
+}
+
+// Merge adds the amounts of the given Budget to this Budget's amounts for the same months.
+func (b *Budget) Merge(o *Budget) {
+	for k, amt := range o.months {
+		b.months[k] += amt
+	}
+}
+
 // SetAmount sets the amount of a Budget in the given month and year.
 func (b *Budget) SetAmount(month time.Month, year int, amount float64) {
 	b.months[monthsKey(month, year)] = amount
@@ -47,3 +60,27 @@ func (b *Budget) Amount(month time.Month, year int) (float64, bool) {
 	amt, ok := b.months[monthsKey(month, year)]
 	return amt, ok
 }
+
+type budgetJSON struct {
+	ID     string             `json:"id"`
+	Name   string             `json:"name"`
+	Months map[string]float64 `json:"months"`
+}
+
+// MarshalJSON dumps the Budget with all of its monthly amounts.
+func (b *Budget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&budgetJSON{ID: b.id, Name: b.name, Months: b.months})
+}
+
+// UnmarshalJSON loads a Budget dumped by MarshalJSON.
+func (b *Budget) UnmarshalJSON(data []byte) error {
+	bj := &budgetJSON{}
+	if err := json.Unmarshal(data, bj); err != nil {
+		return err
+	}
+	if bj.Months == nil {
+		bj.Months = make(map[string]float64)
+	}
+	*b = Budget{id: bj.ID, name: bj.Name, months: bj.Months}
+	return nil
+}
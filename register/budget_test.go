@@ -1,6 +1,8 @@
 package register
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -24,3 +26,44 @@ func TestSetBudget(t *testing.T) {
 		t.Errorf("amount, got: %f, want: %f", got, want)
 	}
 }
+
+func TestMergeBudget(t *testing.T) {
+	b := NewBudget("dst")
+	b.SetAmount(time.March, 2018, 10)
+	o := NewBudget("src")
+	o.SetAmount(time.March, 2018, 5)
+	o.SetAmount(time.April, 2018, 7)
+
+	b.Merge(o)
+	for _, test := range []struct {
+		month time.Month
+		want  float64
+	}{
+		{time.March, 15},
+		{time.April, 7},
+	} {
+		if got, _ := b.Amount(test.month, 2018); got != test.want {
+			t.Errorf("%s: got: %f, want: %f", test.month, got, test.want)
+		}
+	}
+	if got, want := b.Name(), "dst"; got != want {
+		t.Errorf("name: got: %s, want: %s", got, want)
+	}
+}
+
+func TestBudgetJSON(t *testing.T) {
+	b := NewBudget("test")
+	b.SetAmount(time.March, 2018, 42)
+
+	jsonBytes, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unmarshalled := &Budget{}
+	if err := json.Unmarshal(jsonBytes, unmarshalled); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := unmarshalled, b; !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,183 @@
+package register
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// camtParty is a debtor or creditor. Older versions of camt.053 put the name directly under the
+// party, newer ones under Pty.
+type camtParty struct {
+	Name    string `xml:"Nm"`
+	PtyName string `xml:"Pty>Nm"`
+}
+
+func (p *camtParty) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.PtyName
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+func (d *camtDate) parse() (time.Time, error) {
+	if d.Date != "" {
+		return time.Parse("2006-01-02", d.Date)
+	}
+	if len(d.DateTime) >= 10 {
+		return time.Parse("2006-01-02", d.DateTime[:10])
+	}
+	return time.Time{}, fmt.Errorf("no date")
+}
+
+// camtEntry is the part of a camt.053 Ntry element that maps to a Transaction.
+type camtEntry struct {
+	Ref         string `xml:"NtryRef"`
+	Amount      string `xml:"Amt"`
+	CreditDebit string `xml:"CdtDbtInd"`
+	Status      struct {
+		Text string `xml:",chardata"`
+		Code string `xml:"Cd"`
+	} `xml:"Sts"`
+	BookingDate camtDate `xml:"BookgDt"`
+	ValueDate   camtDate `xml:"ValDt"`
+	ServicerRef string   `xml:"AcctSvcrRef"`
+	Info        string   `xml:"AddtlNtryInf"`
+	Details     []struct {
+		ServicerRef string    `xml:"Refs>AcctSvcrRef"`
+		Debtor      camtParty `xml:"RltdPties>Dbtr"`
+		Creditor    camtParty `xml:"RltdPties>Cdtr"`
+		Remittance  []string  `xml:"RmtInf>Ustrd"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+// CamtReader reads Transactions from an ISO 20022 camt.053 bank statement one entry at a time.
+// Only booked entries are read. Entries that cannot be parsed are skipped and collected as
+// RowErrors, up to maxRowErrors of them, with the line of the entry as the Row. Unless Lenient is
+// set, Next returns ErrMalformedRows instead of io.EOF if any entry was skipped.
+type CamtReader struct {
+	Lenient bool
+
+	dec     *xml.Decoder
+	account string
+	rowErrs rowErrors
+}
+
+// NewCamtReader returns a CamtReader for the given camt.053 XML document.
+func NewCamtReader(r io.Reader) *CamtReader {
+	return &CamtReader{dec: xml.NewDecoder(r)}
+}
+
+// RowErrors returns the first maxRowErrors entries skipped so far.
+func (r *CamtReader) RowErrors() []*RowError {
+	return r.rowErrs.errs
+}
+
+// SkippedRows returns the number of entries skipped so far, including those not in RowErrors.
+func (r *CamtReader) SkippedRows() int {
+	return r.rowErrs.count
+}
+
+func (r *CamtReader) parseEntry(row int, e *camtEntry) (*Transaction, *RowError) {
+	date, err := e.BookingDate.parse()
+	if err != nil {
+		if date, err = e.ValueDate.parse(); err != nil {
+			return nil, &RowError{Row: row, Column: "BookgDt", Value: e.BookingDate.Date + e.BookingDate.DateTime, Reason: "booking date must look like 2006-01-02"}
+		}
+	}
+	amount, err := strconv.ParseFloat(strings.TrimSpace(e.Amount), 64)
+	if err != nil {
+		return nil, &RowError{Row: row, Column: "Amt", Value: e.Amount, Reason: "amount must be a number"}
+	}
+	switch e.CreditDebit {
+	case "DBIT":
+		amount = -amount
+	case "CRDT":
+	default:
+		return nil, &RowError{Row: row, Column: "CdtDbtInd", Value: e.CreditDebit, Reason: "credit/debit indicator must be CRDT or DBIT"}
+	}
+
+	ref := e.ServicerRef
+	var party string
+	var remittance []string
+	for _, d := range e.Details {
+		if ref == "" {
+			ref = d.ServicerRef
+		}
+		if party == "" {
+			if amount < 0 {
+				party = d.Creditor.name()
+			} else {
+				party = d.Debtor.name()
+			}
+		}
+		remittance = append(remittance, d.Remittance...)
+	}
+	if ref == "" {
+		ref = e.Ref
+	}
+	if len(remittance) == 0 && e.Info != "" {
+		remittance = []string{e.Info}
+	}
+	desc := strings.Join(strings.Fields(strings.Join(append([]string{party}, remittance...), " ")), " ")
+	return &Transaction{
+		ID:          statementID(r.account, ref, date, desc, amount),
+		Description: desc,
+		Amount:      amount,
+		Date:        &date,
+	}, nil
+}
+
+// Next returns the next booked entry of the statement.
+func (r *CamtReader) Next() (*Transaction, error) {
+	for {
+		row, _ := r.dec.InputPos()
+		tok, err := r.dec.Token()
+		if err == io.EOF {
+			return nil, endOfRows(r.Lenient, &r.rowErrs)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xml.Decoder.Token: %v", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "Acct":
+			acct := &struct {
+				IBAN  string `xml:"Id>IBAN"`
+				Other string `xml:"Id>Othr>Id"`
+			}{}
+			if err := r.dec.DecodeElement(acct, &start); err != nil {
+				return nil, fmt.Errorf("xml.Decoder.DecodeElement(Acct): %v", err)
+			}
+			r.account = acct.IBAN
+			if r.account == "" {
+				r.account = acct.Other
+			}
+		case "Ntry":
+			e := &camtEntry{}
+			if err := r.dec.DecodeElement(e, &start); err != nil {
+				return nil, fmt.Errorf("xml.Decoder.DecodeElement(Ntry): %v", err)
+			}
+			if status := strings.TrimSpace(e.Status.Text + e.Status.Code); status != "" && status != "BOOK" {
+				continue
+			}
+			t, rowErr := r.parseEntry(row, e)
+			if rowErr != nil {
+				r.rowErrs.add(rowErr)
+				continue
+			}
+			return t, nil
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package register
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCamt = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Id>STMT-1</Id>
+      <Acct><Id><IBAN>DE89370400440532013000</IBAN></Id></Acct>
+      <Ntry>
+        <Amt Ccy="EUR">12.50</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2018-05-01</Dt></BookgDt>
+        <ValDt><Dt>2018-05-02</Dt></ValDt>
+        <AcctSvcrRef>REF001</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <RltdPties>
+            <Dbtr><Nm>Me</Nm></Dbtr>
+            <Cdtr><Nm>Stadtwerke</Nm></Cdtr>
+          </RltdPties>
+          <RmtInf><Ustrd>Strom Mai</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">2000.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><DtTm>2018-05-03T10:00:00</DtTm></BookgDt>
+        <NtryDtls><TxDtls>
+          <Refs><AcctSvcrRef>REF002</AcctSvcrRef></Refs>
+          <RltdPties><Dbtr><Pty><Nm>ACME GmbH</Nm></Pty></Dbtr></RltdPties>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">5.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>PDNG</Sts>
+        <BookgDt><Dt>2018-05-04</Dt></BookgDt>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">abc</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2018-05-05</Dt></BookgDt>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+func TestCamtReader(t *testing.T) {
+	r := NewCamtReader(strings.NewReader(testCamt))
+	r.Lenient = true
+	var got []*Transaction
+	for {
+		trans, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		got = append(got, trans)
+	}
+
+	want := []*Transaction{
+		{
+			ID:          "REF-DE89370400440532013000-REF001",
+			Description: "Stadtwerke Strom Mai",
+			Amount:      -12.5,
+			Date:        date(2018, time.May, 1),
+		},
+		{
+			ID:          "REF-DE89370400440532013000-REF002",
+			Description: "ACME GmbH",
+			Amount:      2000,
+			Date:        date(2018, time.May, 3),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	wantErrs := []*RowError{{Row: 38, Column: "Amt", Value: "abc", Reason: "amount must be a number"}}
+	if got, want := r.RowErrors(), wantErrs; !reflect.DeepEqual(got, want) {
+		t.Errorf("row errors: got: %v, want: %v", got, want)
+	}
+}
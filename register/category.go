@@ -0,0 +1,206 @@
+package register
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CategorySeparator separates the levels of a hierarchical Category name, e.g. "Food:Groceries".
+const CategorySeparator = ":"
+
+// ParentCategory returns the name of the parent of the given Category name, or "" if it is a root.
+func ParentCategory(name string) string {
+	i := strings.LastIndex(name, CategorySeparator)
+	if i < 0 {
+		return ""
+	}
+	return name[:i]
+}
+
+// MoveCategory rewrites the given Category name when it is old or one of its descendants so that
+// it lives under new instead. True is returned if the name was rewritten.
+func MoveCategory(name, old, new string) (string, bool) {
+	if name == old {
+		return new, true
+	}
+	if strings.HasPrefix(name, old+CategorySeparator) {
+		return new + name[len(old):], true
+	}
+	return name, false
+}
+
+func validCategory(name string) error {
+	if name == "" {
+		return fmt.Errorf("category name must not be empty")
+	}
+	for _, part := range strings.Split(name, CategorySeparator) {
+		if strings.TrimSpace(part) == "" {
+			return fmt.Errorf("category %q has an empty level", name)
+		}
+	}
+	return nil
+}
+
+// CategoryTree is a managed hierarchy of Category names. Every name in the tree is a full path, and
+// the parent of every name is also in the tree.
+type CategoryTree struct {
+	names map[string]bool
+}
+
+// NewCategoryTree returns a CategoryTree containing the given names and all of their parents.
+// Invalid names are skipped.
+func NewCategoryTree(names ...string) *CategoryTree {
+	ct := &CategoryTree{names: make(map[string]bool)}
+	for _, n := range names {
+		ct.Add(n)
+	}
+	return ct
+}
+
+// Has returns true if the given name is in this tree.
+func (ct *CategoryTree) Has(name string) bool {
+	return ct != nil && ct.names[name]
+}
+
+// Add adds the given name and any missing parents to this tree.
+func (ct *CategoryTree) Add(name string) error {
+	if err := validCategory(name); err != nil {
+		return err
+	}
+	for n := name; n != ""; n = ParentCategory(n) {
+		ct.names[n] = true
+	}
+	return nil
+}
+
+// Names returns every name in this tree, sorted so that parents precede their children.
+func (ct *CategoryTree) Names() []string {
+	if ct == nil {
+		return nil
+	}
+	var names []string
+	for n := range ct.names {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Children returns the sorted names of the direct children of the given name. The roots of the
+// tree are the children of "".
+func (ct *CategoryTree) Children(name string) []string {
+	if ct == nil {
+		return nil
+	}
+	var children []string
+	for n := range ct.names {
+		if ParentCategory(n) == name {
+			children = append(children, n)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+// Remove removes a name with no children from this tree.
+func (ct *CategoryTree) Remove(name string) error {
+	if !ct.Has(name) {
+		return fmt.Errorf("no category with name: %s", name)
+	}
+	if len(ct.Children(name)) > 0 {
+		return fmt.Errorf("category %s has sub-categories", name)
+	}
+	delete(ct.names, name)
+	return nil
+}
+
+func (ct *CategoryTree) move(old, new string) {
+	var moved []string
+	for n := range ct.names {
+		if m, ok := MoveCategory(n, old, new); ok {
+			delete(ct.names, n)
+			moved = append(moved, m)
+		}
+	}
+	for _, n := range moved {
+		ct.Add(n)
+	}
+}
+
+func (ct *CategoryTree) checkMove(old, new string) error {
+	if !ct.Has(old) {
+		return fmt.Errorf("no category with name: %s", old)
+	}
+	if err := validCategory(new); err != nil {
+		return err
+	}
+	if _, ok := MoveCategory(new, old, new); ok {
+		return fmt.Errorf("cannot move category %s into itself", old)
+	}
+	return nil
+}
+
+// Rename renames the given category, along with all of its descendants, to a name that is not
+// already in the tree.
+func (ct *CategoryTree) Rename(old, new string) error {
+	if err := ct.checkMove(old, new); err != nil {
+		return err
+	}
+	if ct.Has(new) {
+		return fmt.Errorf("there is already a category with name: %s", new)
+	}
+	ct.move(old, new)
+	return nil
+}
+
+// Merge folds the src category into the existing dst category. The descendants of src become
+// descendants of dst.
+func (ct *CategoryTree) Merge(src, dst string) error {
+	if err := ct.checkMove(src, dst); err != nil {
+		return err
+	}
+	if !ct.Has(dst) {
+		return fmt.Errorf("no category with name: %s", dst)
+	}
+	ct.move(src, dst)
+	return nil
+}
+
+// CategoryTotal is the amount spent against a single Category. Total includes the amounts of all
+// of the Category's descendants.
+type CategoryTotal struct {
+	Name   string  `json:"name"`
+	Parent string  `json:"parent"`
+	Amount float64 `json:"amount"`
+	Total  float64 `json:"total"`
+}
+
+// RollUp totals the Category amounts of the given Transactions for every name in this tree, summing
+// each child's Total into its parent. Categories that are not in the tree, and linked transfers,
+// are ignored.
+func (ct *CategoryTree) RollUp(trans []*Transaction) []*CategoryTotal {
+	totals := make(map[string]*CategoryTotal)
+	var res []*CategoryTotal
+	for _, n := range ct.Names() {
+		total := &CategoryTotal{Name: n, Parent: ParentCategory(n)}
+		totals[n] = total
+		res = append(res, total)
+	}
+	for _, t := range trans {
+		if t.IsTransfer() {
+			continue
+		}
+		for _, c := range t.Category {
+			total, ok := totals[c.Name]
+			if !ok {
+				continue
+			}
+			total.Amount += c.Amount
+			for n := c.Name; n != ""; n = ParentCategory(n) {
+				totals[n].Total += c.Amount
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,136 @@
+package register
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCategoryTreeAdd(t *testing.T) {
+	ct := NewCategoryTree("Food:Groceries", "Bills")
+	if got, want := ct.Names(), []string{"Bills", "Food", "Food:Groceries"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("names: got: %v, want: %v", got, want)
+	}
+	if got, want := ct.Children(""), []string{"Bills", "Food"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("roots: got: %v, want: %v", got, want)
+	}
+	for _, bad := range []string{"", "Food:", ":Food", "Food::Groceries"} {
+		if err := ct.Add(bad); err == nil {
+			t.Errorf("Add(%q): expected non-nil error", bad)
+		}
+	}
+}
+
+func TestNilCategoryTree(t *testing.T) {
+	var ct *CategoryTree
+	if ct.Has("Food") {
+		t.Error("a nil tree should have no names")
+	}
+	if got := ct.Names(); got != nil {
+		t.Errorf("names: got: %v, want: nil", got)
+	}
+	if got := ct.Children(""); got != nil {
+		t.Errorf("roots: got: %v, want: nil", got)
+	}
+}
+
+func TestCategoryTreeRemove(t *testing.T) {
+	ct := NewCategoryTree("Food:Groceries")
+	if err := ct.Remove("Food"); err == nil {
+		t.Error("expected non-nil error removing a category with children")
+	}
+	if err := ct.Remove("Food:Groceries"); err != nil {
+		t.Errorf("Remove(leaf): %v", err)
+	}
+	if err := ct.Remove("Food:Groceries"); err == nil {
+		t.Error("expected non-nil error removing an unknown category")
+	}
+}
+
+func TestCategoryTreeRenameMerge(t *testing.T) {
+	tests := []struct {
+		label     string
+		merge     bool
+		from, to  string
+		wantErr   bool
+		wantNames []string
+	}{
+		{
+			label:     "rename subtree",
+			from:      "Food",
+			to:        "Eating",
+			wantNames: []string{"Bills", "Eating", "Eating:Groceries", "Eating:Restaurants"},
+		},
+		{
+			label:     "rename under new parent",
+			from:      "Food:Groceries",
+			to:        "Home:Groceries",
+			wantNames: []string{"Bills", "Food", "Food:Restaurants", "Home", "Home:Groceries"},
+		},
+		{
+			label:   "rename onto existing",
+			from:    "Food",
+			to:      "Bills",
+			wantErr: true,
+		},
+		{
+			label:   "rename into itself",
+			from:    "Food",
+			to:      "Food:Other",
+			wantErr: true,
+		},
+		{
+			label:     "merge",
+			merge:     true,
+			from:      "Food:Restaurants",
+			to:        "Food:Groceries",
+			wantNames: []string{"Bills", "Food", "Food:Groceries"},
+		},
+		{
+			label:   "merge into unknown",
+			merge:   true,
+			from:    "Food",
+			to:      "Travel",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			ct := NewCategoryTree("Bills", "Food:Groceries", "Food:Restaurants")
+			var err error
+			if test.merge {
+				err = ct.Merge(test.from, test.to)
+			} else {
+				err = ct.Rename(test.from, test.to)
+			}
+			if got, want := err != nil, test.wantErr; got != want {
+				t.Fatalf("wantErr: err: %v, got: %t, want: %t", err, got, want)
+			}
+			if test.wantErr {
+				return
+			}
+			if got, want := ct.Names(), test.wantNames; !reflect.DeepEqual(got, want) {
+				t.Errorf("names: got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCategoryTreeRollUp(t *testing.T) {
+	ct := NewCategoryTree("Food:Groceries", "Food:Restaurants", "Bills")
+	trans := []*Transaction{
+		{Category: []*Category{{Name: "Food:Groceries", Amount: -10}}},
+		{Category: []*Category{{Name: "Food:Restaurants", Amount: -5}}},
+		{Category: []*Category{{Name: "Food", Amount: -1}, {Name: "Bills", Amount: -20}}},
+		{Category: []*Category{{Name: "Phantom", Amount: -100}}},
+	}
+	want := []*CategoryTotal{
+		{Name: "Bills", Amount: -20, Total: -20},
+		{Name: "Food", Amount: -1, Total: -16},
+		{Name: "Food:Groceries", Parent: "Food", Amount: -10, Total: -10},
+		{Name: "Food:Restaurants", Parent: "Food", Amount: -5, Total: -5},
+	}
+	if got := ct.RollUp(trans); !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,170 @@
+package register
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// DefaultDuplicateDays is the default number of days two copies of a transaction may be apart.
+	DefaultDuplicateDays = 5
+	// MinDuplicateScore is the lowest score at which a pair is reported as a suspected duplicate.
+	MinDuplicateScore = 0.75
+
+	// How far apart, as a fraction of the larger amount, two copies may be. Covers a pending
+	// charge that posts with a tip added.
+	duplicateAmountSlack = 0.25
+)
+
+// DuplicatePair is a pair of Transactions that look like two copies of the same transaction. A is
+// never dated after B. Score is between MinDuplicateScore and 1, higher meaning more alike.
+type DuplicatePair struct {
+	A     *Transaction `json:"a"`
+	B     *Transaction `json:"b"`
+	Score float64      `json:"score"`
+}
+
+// DuplicateKey identifies the pair of Transactions with the given IDs regardless of their order.
+func DuplicateKey(a, b string) string {
+	if b < a {
+		a, b = b, a
+	}
+	return a + "|" + b
+}
+
+// Key returns the DuplicateKey of this pair.
+func (p *DuplicatePair) Key() string {
+	return DuplicateKey(p.A.ID, p.B.ID)
+}
+
+func bigrams(s string) map[string]int {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	rs := []rune(b.String())
+	grams := make(map[string]int)
+	for i := 0; i+1 < len(rs); i++ {
+		grams[string(rs[i:i+2])]++
+	}
+	return grams
+}
+
+// Similarity returns the Dice coefficient of the letter and digit bigrams of two descriptions,
+// ignoring case, punctuation and spacing: 1 for identical text and 0 for nothing in common.
+func Similarity(a, b string) float64 {
+	ga, gb := bigrams(a), bigrams(b)
+	var total, shared int
+	for g, n := range ga {
+		total += n
+		if m, ok := gb[g]; ok {
+			if m < n {
+				n = m
+			}
+			shared += n
+		}
+	}
+	for _, n := range gb {
+		total += n
+	}
+	if total == 0 {
+		if strings.EqualFold(a, b) {
+			return 1
+		}
+		return 0
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+// duplicateScore scores how alike two Transactions are by amount equality, date proximity and
+// description similarity. It returns 0 for pairs that cannot be copies of each other.
+func duplicateScore(a, b *Transaction, days int) float64 {
+	if a.ID == b.ID || a.Date == nil || b.Date == nil || (a.IsTransfer() && b.IsTransfer()) {
+		return 0
+	}
+	if a.Account != "" && b.Account != "" && a.Account != b.Account {
+		return 0
+	}
+	if (a.Amount < 0) != (b.Amount < 0) {
+		return 0
+	}
+	apart := daysApart(a.Date, b.Date)
+	if apart > days {
+		return 0
+	}
+
+	amount := 1.0
+	if cents(a.Amount) != cents(b.Amount) {
+		diff := math.Abs(a.Amount - b.Amount)
+		larger := math.Max(math.Abs(a.Amount), math.Abs(b.Amount))
+		if diff > larger*duplicateAmountSlack {
+			return 0
+		}
+		amount = 1 - diff/larger
+	}
+	date := 1 - float64(apart)/float64(days+1)
+	desc := Similarity(a.Description, b.Description)
+	if a.Payee != "" && b.Payee != "" {
+		desc = math.Max(desc, Similarity(a.Payee, b.Payee))
+	}
+	return 0.4*amount + 0.3*date + 0.3*desc
+}
+
+// FindDuplicates returns pairs of Transactions, at most the given number of days apart, that score
+// at least MinDuplicateScore as copies of each other, best first. Pairs with different accounts or
+// opposite signs are never reported.
+func FindDuplicates(trans []*Transaction, days int) []*DuplicatePair {
+	var dated []*Transaction
+	for _, t := range trans {
+		if t.Date != nil {
+			dated = append(dated, t)
+		}
+	}
+	sort.SliceStable(dated, func(i, j int) bool {
+		return dated[i].Date.Before(*dated[j].Date)
+	})
+
+	var pairs []*DuplicatePair
+	for i, a := range dated {
+		for _, b := range dated[i+1:] {
+			if daysApart(a.Date, b.Date) > days {
+				break
+			}
+			if score := duplicateScore(a, b, days); score >= MinDuplicateScore {
+				pairs = append(pairs, &DuplicatePair{A: a, B: b, Score: score})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Score > pairs[j].Score
+	})
+	return pairs
+}
+
+// MergeDuplicate folds the tags, notes, attachments and any missing details of drop into keep.
+// The caller is responsible for discarding drop.
+func MergeDuplicate(keep, drop *Transaction) {
+	for _, tag := range drop.Tags {
+		keep.AddTag(tag)
+	}
+	switch {
+	case keep.Notes == "":
+		keep.Notes = drop.Notes
+	case drop.Notes != "" && drop.Notes != keep.Notes:
+		keep.Notes += "\n" + drop.Notes
+	}
+	keep.Attachments = append(keep.Attachments, drop.Attachments...)
+	if len(keep.Category) == 0 {
+		keep.Category = drop.Category
+	}
+	if keep.Account == "" {
+		keep.Account = drop.Account
+	}
+	if keep.Payee == "" {
+		keep.Payee = drop.Payee
+	}
+}
@@ -0,0 +1,109 @@
+package register
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{a: "COSTCO WHSE", b: "costco whse", want: 1},
+		{a: "Netflix", b: "Spotify", want: 0},
+		{a: "", b: "", want: 1},
+		{a: "ab", b: "abc", want: 2.0 / 3},
+	}
+	for _, test := range tests {
+		if got, want := Similarity(test.a, test.b), test.want; got != want {
+			t.Errorf("Similarity(%q, %q): got: %f, want: %f", test.a, test.b, got, want)
+		}
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	var (
+		pending = &Transaction{ID: "pending", Description: "SQ *BLUE BOTTLE", Payee: "Blue Bottle", Amount: -5.5, Date: date(2018, time.May, 1)}
+		posted  = &Transaction{ID: "posted", Description: "SQ *BLUE BOTTLE COFFEE 0501", Payee: "Blue Bottle Coffee", Amount: -5.5, Date: date(2018, time.May, 3)}
+		tipped  = &Transaction{ID: "tipped", Description: "SQ *BLUE BOTTLE", Payee: "Blue Bottle", Amount: -6.5, Date: date(2018, time.May, 1)}
+		later   = &Transaction{ID: "later", Description: "SQ *BLUE BOTTLE", Amount: -5.5, Date: date(2018, time.May, 20)}
+		other   = &Transaction{ID: "other", Description: "NETFLIX.COM", Amount: -5.5, Date: date(2018, time.May, 2)}
+		refund  = &Transaction{ID: "refund", Description: "SQ *BLUE BOTTLE", Amount: 5.5, Date: date(2018, time.May, 1)}
+		savings = &Transaction{ID: "savings", Description: "SQ *BLUE BOTTLE", Amount: -5.5, Date: date(2018, time.May, 1), Account: "savings"}
+		checks  = &Transaction{ID: "checks", Description: "SQ *BLUE BOTTLE", Amount: -5.5, Date: date(2018, time.May, 1), Account: "checking"}
+	)
+
+	tests := []struct {
+		label string
+		trans []*Transaction
+		want  []string
+	}{
+		{
+			label: "pending then posted",
+			trans: []*Transaction{posted, pending},
+			want:  []string{DuplicateKey("pending", "posted")},
+		},
+		{
+			label: "tip added",
+			trans: []*Transaction{pending, tipped},
+			want:  []string{DuplicateKey("pending", "tipped")},
+		},
+		{
+			label: "too far apart",
+			trans: []*Transaction{pending, later},
+		},
+		{
+			label: "different description",
+			trans: []*Transaction{pending, other},
+		},
+		{
+			label: "opposite signs",
+			trans: []*Transaction{pending, refund},
+		},
+		{
+			label: "different accounts",
+			trans: []*Transaction{savings, checks},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			var got []string
+			for _, p := range FindDuplicates(test.trans, DefaultDuplicateDays) {
+				if p.A.Date.After(*p.B.Date) {
+					t.Errorf("pair %s: A is dated after B", p.Key())
+				}
+				got = append(got, p.Key())
+			}
+			if want := test.want; !reflect.DeepEqual(got, want) {
+				t.Errorf("got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMergeDuplicate(t *testing.T) {
+	keep := &Transaction{ID: "keep", Tags: []string{"coffee"}, Notes: "morning"}
+	drop := &Transaction{
+		ID:          "drop",
+		Tags:        []string{"work"},
+		Notes:       "with Sam",
+		Attachments: []*Attachment{{ID: "ATT-1"}},
+		Account:     "checking",
+		Category:    []*Category{{Name: "Food", Amount: -5.5}},
+	}
+	MergeDuplicate(keep, drop)
+
+	want := &Transaction{
+		ID:          "keep",
+		Tags:        []string{"coffee", "work"},
+		Notes:       "morning\nwith Sam",
+		Attachments: []*Attachment{{ID: "ATT-1"}},
+		Account:     "checking",
+		Category:    []*Category{{Name: "Food", Amount: -5.5}},
+	}
+	if got := keep; !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+}
@@ -0,0 +1,253 @@
+package register
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Negative number styles.
+const (
+	// NegativeMinus writes negative amounts with a leading minus sign, like -45.00.
+	NegativeMinus = "minus"
+	// NegativeParens writes negative amounts in parentheses, like (45.00).
+	NegativeParens = "parens"
+	// NegativeTrailing writes negative amounts with a trailing minus sign, like 45.00-.
+	NegativeTrailing = "trailing"
+)
+
+// sampleRows is how many rows of a file are used to detect its CSVFormat. While their dates read
+// as well day first as month first, up to maxSampleRows rows are sampled to tell them apart.
+const (
+	sampleRows    = 20
+	maxSampleRows = 1000
+)
+
+// DateLayouts are the date layouts that format detection tries, in order of preference.
+var DateLayouts = []string{
+	"1/2/2006",
+	"2/1/2006",
+	"2006-01-02",
+	"2006/01/02",
+	"2.1.2006",
+	"2-1-2006",
+	"1/2/06",
+	"2/1/06",
+	"2.1.06",
+	"Jan 2, 2006",
+	"2 Jan 2006",
+	"02-Jan-2006",
+	"January 2, 2006",
+}
+
+// CSVFormat describes how a CSV file writes its dates and amounts. DateLayout is a time.Parse
+// layout. Decimal and Thousands are the separators of amounts, with an empty Thousands meaning
+// none, and Negative is one of the Negative styles.
+type CSVFormat struct {
+	DateLayout string `json:"dateLayout"`
+	Decimal    string `json:"decimal"`
+	Thousands  string `json:"thousands"`
+	Negative   string `json:"negative"`
+}
+
+// DefaultCSVFormat is the format of US bank exports, and the fallback when nothing is detected.
+var DefaultCSVFormat = CSVFormat{DateLayout: "1/2/2006", Decimal: ".", Thousands: ",", Negative: NegativeMinus}
+
+// Validate returns an error if this CSVFormat cannot be used to parse amounts.
+func (f *CSVFormat) Validate() error {
+	if f.Decimal != "." && f.Decimal != "," {
+		return fmt.Errorf("decimal separator must be . or , but got %q", f.Decimal)
+	}
+	switch f.Thousands {
+	case "", ",", ".", " ", "'":
+	default:
+		return fmt.Errorf("thousands separator must be one of , . ' or a space, but got %q", f.Thousands)
+	}
+	if f.Thousands == f.Decimal {
+		return fmt.Errorf("decimal and thousands separators must differ")
+	}
+	switch f.Negative {
+	case NegativeMinus, NegativeParens, NegativeTrailing:
+	default:
+		return fmt.Errorf("unknown negative number style: %q", f.Negative)
+	}
+	return nil
+}
+
+// detectDateLayout picks the first of DateLayouts that parses the most of the given dates, so
+// that a few malformed rows do not throw off detection. It returns false if another layout parses
+// as many of them but reads any of them as a different date, as 5/1/2018 is either May 1st or
+// January 5th.
+func detectDateLayout(dates []string) (string, bool) {
+	counts := make([]int, len(DateLayouts))
+	best := -1
+	for i, layout := range DateLayouts {
+		for _, d := range dates {
+			if _, err := time.Parse(layout, strings.TrimSpace(d)); err == nil {
+				counts[i]++
+			}
+		}
+		if counts[i] > 0 && (best < 0 || counts[i] > counts[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return DefaultCSVFormat.DateLayout, true
+	}
+	for i, layout := range DateLayouts {
+		if i == best || counts[i] != counts[best] {
+			continue
+		}
+		for _, d := range dates {
+			d = strings.TrimSpace(d)
+			a, errA := time.Parse(DateLayouts[best], d)
+			b, errB := time.Parse(layout, d)
+			if errA == nil && errB == nil && !a.Equal(b) {
+				return DateLayouts[best], false
+			}
+		}
+	}
+	return DateLayouts[best], true
+}
+
+func detectNegative(amounts []string) string {
+	for _, a := range amounts {
+		switch {
+		case strings.HasPrefix(a, "(") && strings.HasSuffix(a, ")"):
+			return NegativeParens
+		case strings.HasSuffix(a, "-"):
+			return NegativeTrailing
+		}
+	}
+	return NegativeMinus
+}
+
+// detectDecimal votes on the decimal separator. A separator followed by one, two or more than
+// three digits at the end of an amount is a decimal point, and when both appear the last is.
+func detectDecimal(amounts []string) string {
+	var dots, commas int
+	for _, a := range amounts {
+		i := strings.LastIndexAny(a, ".,")
+		if i < 0 {
+			continue
+		}
+		sep := a[i]
+		digits := 0
+		for _, r := range a[i+1:] {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		other := byte(',')
+		if sep == ',' {
+			other = '.'
+		}
+		if digits == 3 && strings.IndexByte(a[:i], other) < 0 {
+			// Ambiguous, like 1,234 or 1.234.
+			continue
+		}
+		if sep == ',' {
+			commas++
+		} else {
+			dots++
+		}
+	}
+	if commas > dots {
+		return ","
+	}
+	return "."
+}
+
+func detectThousands(amounts []string, decimal string) string {
+	for _, sep := range []string{",", ".", "'", " "} {
+		if sep == decimal {
+			continue
+		}
+		for _, a := range amounts {
+			if strings.Contains(a, sep) {
+				return sep
+			}
+		}
+	}
+	if decimal == "," {
+		return "."
+	}
+	return ","
+}
+
+// DetectCSVFormat infers the format of a CSV file from sampled date and amount values. Any
+// non-empty field of override is used as-is instead of being inferred. An error is returned if the
+// dates read as well day first as month first, and the date layout must be given in override.
+func DetectCSVFormat(dates, amounts []string, override CSVFormat) (CSVFormat, error) {
+	var trimmed []string
+	for _, a := range amounts {
+		if a = strings.TrimSpace(a); a != "" {
+			trimmed = append(trimmed, a)
+		}
+	}
+	f := override
+	if f.DateLayout == "" {
+		layout, ok := detectDateLayout(dates)
+		if !ok {
+			return f, fmt.Errorf("dates like %s could be day or month first, so the date layout must be given", ambiguousDate(dates, layout))
+		}
+		f.DateLayout = layout
+	}
+	if f.Negative == "" {
+		f.Negative = detectNegative(trimmed)
+	}
+	if f.Decimal == "" {
+		f.Decimal = detectDecimal(trimmed)
+	}
+	if f.Thousands == "" {
+		f.Thousands = detectThousands(trimmed, f.Decimal)
+	}
+	return f, nil
+}
+
+// ambiguousDate returns the first of the given dates that layout parses, as an example.
+func ambiguousDate(dates []string, layout string) string {
+	for _, d := range dates {
+		d = strings.TrimSpace(d)
+		if _, err := time.Parse(layout, d); err == nil {
+			return d
+		}
+	}
+	return ""
+}
+
+// ParseAmount parses an amount written in this CSVFormat. Currency symbols and spaces are ignored.
+func (f *CSVFormat) ParseAmount(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	negative := false
+	switch {
+	case f.Negative == NegativeParens && strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")"):
+		negative = true
+		s = s[1 : len(s)-1]
+	case f.Negative == NegativeTrailing && strings.HasSuffix(s, "-"):
+		negative = true
+		s = s[:len(s)-1]
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case unicode.IsDigit(r), r == '-', r == '+':
+			b.WriteRune(r)
+		case string(r) == f.Decimal:
+			b.WriteByte('.')
+		case string(r) == f.Thousands, unicode.IsSpace(r), unicode.Is(unicode.Sc, r):
+		default:
+			return 0, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+	amount, err := strconv.ParseFloat(b.String(), 64)
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
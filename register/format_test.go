@@ -0,0 +1,109 @@
+package register
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectCSVFormat(t *testing.T) {
+	tests := []struct {
+		label    string
+		dates    []string
+		amounts  []string
+		override CSVFormat
+		want     CSVFormat
+		wantErr  bool
+	}{
+		{
+			label:   "us",
+			dates:   []string{"5/1/2018", "5/25/2018"},
+			amounts: []string{"-5.50", "", "", "1,000.00"},
+			want:    CSVFormat{DateLayout: "1/2/2006", Decimal: ".", Thousands: ",", Negative: NegativeMinus},
+		},
+		{
+			label:   "day first",
+			dates:   []string{"1/5/2018", "25/5/2018"},
+			amounts: []string{"-5.50"},
+			want:    CSVFormat{DateLayout: "2/1/2006", Decimal: ".", Thousands: ",", Negative: NegativeMinus},
+		},
+		{
+			label:   "german",
+			dates:   []string{"01.05.2018", "02.05.2018"},
+			amounts: []string{"-1.234,56", "12,00"},
+			want:    CSVFormat{DateLayout: "2.1.2006", Decimal: ",", Thousands: ".", Negative: NegativeMinus},
+		},
+		{
+			label:   "iso with parens",
+			dates:   []string{"2018-05-01", "bad"},
+			amounts: []string{"(45.00)", "1 234.50"},
+			want:    CSVFormat{DateLayout: "2006-01-02", Decimal: ".", Thousands: " ", Negative: NegativeParens},
+		},
+		{
+			label:   "long dates with trailing minus",
+			dates:   []string{"Jan 2, 2018"},
+			amounts: []string{"45.00-"},
+			want:    CSVFormat{DateLayout: "Jan 2, 2006", Decimal: ".", Thousands: ",", Negative: NegativeTrailing},
+		},
+		{
+			label:    "override",
+			dates:    []string{"5/1/2018"},
+			amounts:  []string{"1.234"},
+			override: CSVFormat{DateLayout: "2/1/2006", Decimal: ","},
+			want:     CSVFormat{DateLayout: "2/1/2006", Decimal: ",", Thousands: ".", Negative: NegativeMinus},
+		},
+		{
+			label:   "day or month first",
+			dates:   []string{"5/1/2018", "5/2/2018"},
+			amounts: []string{"-5.50"},
+			wantErr: true,
+		},
+		{
+			label:    "day or month first with override",
+			dates:    []string{"5/1/2018", "5/2/2018"},
+			amounts:  []string{"-5.50"},
+			override: CSVFormat{DateLayout: "2/1/2006"},
+			want:     CSVFormat{DateLayout: "2/1/2006", Decimal: ".", Thousands: ",", Negative: NegativeMinus},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			got, err := DetectCSVFormat(test.dates, test.amounts, test.override)
+			if gotErr, wantErr := err != nil, test.wantErr; gotErr != wantErr {
+				t.Fatalf("error: got: %t, want: %t, err: %v", gotErr, wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if want := test.want; !reflect.DeepEqual(got, want) {
+				t.Errorf("got: %+v, want: %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		format  CSVFormat
+		amount  string
+		want    float64
+		wantErr bool
+	}{
+		{format: DefaultCSVFormat, amount: "-1,234.56", want: -1234.56},
+		{format: DefaultCSVFormat, amount: "$12", want: 12},
+		{format: CSVFormat{Decimal: ",", Thousands: ".", Negative: NegativeMinus}, amount: "-1.234,56", want: -1234.56},
+		{format: CSVFormat{Decimal: ".", Thousands: ",", Negative: NegativeParens}, amount: "(45.00)", want: -45},
+		{format: CSVFormat{Decimal: ".", Thousands: "'", Negative: NegativeTrailing}, amount: "1'000.50-", want: -1000.5},
+		{format: DefaultCSVFormat, amount: "12abc", wantErr: true},
+		{format: DefaultCSVFormat, amount: "", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := test.format.ParseAmount(test.amount)
+		if gotErr, wantErr := err != nil, test.wantErr; gotErr != wantErr {
+			t.Errorf("ParseAmount(%q): error: got: %t, want: %t, err: %v", test.amount, gotErr, wantErr, err)
+			continue
+		}
+		if want := test.want; got != want {
+			t.Errorf("ParseAmount(%q): got: %f, want: %f", test.amount, got, want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package register
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	mt940TagRE = regexp.MustCompile(`^:(\d{2}[A-Z]?):(.*)$`)
+	// Value date, optional entry date, debit/credit mark, optional funds code, amount, transaction
+	// type and the references.
+	mt940LineRE = regexp.MustCompile(`^(\d{6})(\d{4})?(RD|RC|D|C)([A-Z])?(\d+,\d*)([A-Z][A-Z0-9]{3})(.*)$`)
+	// Subfields of structured German :86: information, like ?20.
+	mt940SubfieldRE = regexp.MustCompile(`\?(\d{2})`)
+)
+
+// mt940Entry is a :61: statement line along with its :86: information.
+type mt940Entry struct {
+	row     int
+	account string
+	line    string
+	info    string
+}
+
+// MT940Reader reads Transactions from a SWIFT MT940 bank statement one statement line at a time.
+// Statement lines that cannot be parsed are skipped and collected as RowErrors, up to maxRowErrors
+// of them, with the line of the :61: field as the Row. Unless Lenient is set, Next returns
+// ErrMalformedRows instead of io.EOF if any statement line was skipped.
+type MT940Reader struct {
+	Lenient bool
+
+	scan    *bufio.Scanner
+	line    int
+	account string
+	// The field being read, and the line it started on.
+	tag, value string
+	tagRow     int
+	pending    *mt940Entry
+	rowErrs    rowErrors
+}
+
+// NewMT940Reader returns an MT940Reader for the given MT940 statement file.
+func NewMT940Reader(r io.Reader) *MT940Reader {
+	return &MT940Reader{scan: bufio.NewScanner(r)}
+}
+
+// RowErrors returns the first maxRowErrors statement lines skipped so far.
+func (r *MT940Reader) RowErrors() []*RowError {
+	return r.rowErrs.errs
+}
+
+// SkippedRows returns the number of statement lines skipped so far, including those not in
+// RowErrors.
+func (r *MT940Reader) SkippedRows() int {
+	return r.rowErrs.count
+}
+
+// mt940Info splits :86: information into the counterparty and the remittance information. Both
+// structured German information, with ?20 to ?29 and ?60 to ?63 for the remittance and ?32 and ?33
+// for the counterparty, and free text are understood.
+func mt940Info(info string) (string, string) {
+	info = strings.Replace(info, "\n", "", -1)
+	locs := mt940SubfieldRE.FindAllStringSubmatchIndex(info, -1)
+	if len(locs) == 0 {
+		return "", info
+	}
+	var party, remittance []string
+	for i, loc := range locs {
+		end := len(info)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		code, _ := strconv.Atoi(info[loc[2]:loc[3]])
+		value := info[loc[1]:end]
+		switch {
+		case code >= 20 && code <= 29, code >= 60 && code <= 63:
+			remittance = append(remittance, value)
+		case code == 32, code == 33:
+			party = append(party, value)
+		}
+	}
+	return strings.Join(party, ""), strings.Join(remittance, " ")
+}
+
+func (r *MT940Reader) parseEntry(e *mt940Entry) (*Transaction, *RowError) {
+	lines := strings.SplitN(e.line, "\n", 2)
+	m := mt940LineRE.FindStringSubmatch(lines[0])
+	if m == nil {
+		return nil, &RowError{Row: e.row, Column: ":61:", Value: lines[0], Reason: "statement line must look like 180501D12,34NTRF"}
+	}
+	valueDate, err := time.Parse("060102", m[1])
+	if err != nil {
+		return nil, &RowError{Row: e.row, Column: ":61:", Value: m[1], Reason: "value date must look like 060102"}
+	}
+	date := valueDate
+	if m[2] != "" {
+		entry, err := time.Parse("0102", m[2])
+		if err != nil {
+			return nil, &RowError{Row: e.row, Column: ":61:", Value: m[2], Reason: "entry date must look like 0102"}
+		}
+		year := valueDate.Year()
+		// The entry date may fall in the year before or after the value date.
+		switch {
+		case entry.Month() == time.January && valueDate.Month() == time.December:
+			year++
+		case entry.Month() == time.December && valueDate.Month() == time.January:
+			year--
+		}
+		date = time.Date(year, entry.Month(), entry.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	amount, err := strconv.ParseFloat(strings.Replace(m[5], ",", ".", 1), 64)
+	if err != nil {
+		return nil, &RowError{Row: e.row, Column: ":61:", Value: m[5], Reason: "amount must be a number"}
+	}
+	if m[3] == "D" || m[3] == "RC" {
+		amount = -amount
+	}
+
+	refs := strings.SplitN(m[7], "//", 2)
+	ref := refs[0]
+	if len(refs) == 2 && refs[1] != "" {
+		ref = refs[1]
+	}
+	party, remittance := mt940Info(e.info)
+	if party == "" && remittance == "" && len(lines) == 2 {
+		remittance = lines[1]
+	}
+	desc := strings.Join(strings.Fields(party+" "+remittance), " ")
+	return &Transaction{
+		ID:          statementID(e.account, strings.TrimSpace(ref), date, desc, amount),
+		Description: desc,
+		Amount:      amount,
+		Date:        &date,
+	}, nil
+}
+
+// endField handles the field that was being read. A finished statement line is returned.
+func (r *MT940Reader) endField() *mt940Entry {
+	var done *mt940Entry
+	switch r.tag {
+	case "25":
+		r.account = strings.TrimSpace(r.value)
+	case "61":
+		done = r.pending
+		r.pending = &mt940Entry{row: r.tagRow, account: r.account, line: r.value}
+	case "86":
+		if r.pending != nil {
+			r.pending.info = r.value
+		}
+	case "":
+	default:
+		// Any other field, like the closing balance, ends the statement line.
+		done, r.pending = r.pending, nil
+	}
+	r.tag, r.value = "", ""
+	return done
+}
+
+// nextEntry returns the next complete statement line, or nil at the end of the file.
+func (r *MT940Reader) nextEntry() (*mt940Entry, error) {
+	for r.scan.Scan() {
+		r.line++
+		line := strings.TrimRight(r.scan.Text(), "\r")
+		var done *mt940Entry
+		if m := mt940TagRE.FindStringSubmatch(line); m != nil {
+			done = r.endField()
+			r.tag, r.value, r.tagRow = m[1], m[2], r.line
+		} else if line == "-" || line == "-}" || strings.HasPrefix(line, "{") {
+			// The end of a message, or a SWIFT block header.
+			if done = r.endField(); done == nil {
+				done, r.pending = r.pending, nil
+			}
+		} else if r.tag != "" {
+			r.value += "\n" + line
+		}
+		if done != nil {
+			return done, nil
+		}
+	}
+	if err := r.scan.Err(); err != nil {
+		return nil, fmt.Errorf("bufio.Scanner.Scan: %v", err)
+	}
+	if done := r.endField(); done != nil {
+		return done, nil
+	}
+	done := r.pending
+	r.pending = nil
+	return done, nil
+}
+
+// Next returns the next statement line of the file.
+func (r *MT940Reader) Next() (*Transaction, error) {
+	for {
+		e, err := r.nextEntry()
+		if err != nil {
+			return nil, err
+		}
+		if e == nil {
+			return nil, endOfRows(r.Lenient, &r.rowErrs)
+		}
+		t, rowErr := r.parseEntry(e)
+		if rowErr != nil {
+			r.rowErrs.add(rowErr)
+			continue
+		}
+		return t, nil
+	}
+}
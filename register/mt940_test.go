@@ -0,0 +1,74 @@
+package register
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testMT940 = `{1:F01BANKDEFFAXXX0000000000}{2:I940BANKDEFFXXXXN}{4:
+:20:STARTUMS
+:25:37040044/0532013000
+:28C:00001/001
+:60F:C171229EUR1000,00
+:61:1712291229DR12,50NDDTNONREF//BANKREF1
+:86:105?00SEPA-LASTSCHRIFT?20Strom Dezember?21Vertrag 42?32STADTWERKE
+:61:1712310102CR2000,NTRFNONREF
+Gehalt Januar
+:61:171231XD1,00NTRFNONREF
+:62F:C180102EUR2987,50
+-}
+`
+
+func TestMT940Reader(t *testing.T) {
+	r := NewMT940Reader(strings.NewReader(testMT940))
+	r.Lenient = true
+	var got []*Transaction
+	for {
+		trans, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		got = append(got, trans)
+	}
+
+	want := []*Transaction{
+		{
+			ID:          "REF-37040044/0532013000-BANKREF1",
+			Description: "STADTWERKE Strom Dezember Vertrag 42",
+			Amount:      -12.5,
+			Date:        date(2017, time.December, 29),
+		},
+		{
+			ID:          "TRANS-2018-01-02 00:00:00 +0000 UTC-Gehalt Januar-2000.000000",
+			Description: "Gehalt Januar",
+			Amount:      2000,
+			Date:        date(2018, time.January, 2),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if got, want := len(r.RowErrors()), 1; got != want {
+		t.Fatalf("row errors: got: %d, want: %d", got, want)
+	}
+	if got, want := r.RowErrors()[0].Row, 10; got != want {
+		t.Errorf("row error row: got: %d, want: %d", got, want)
+	}
+}
+
+func TestMT940Strict(t *testing.T) {
+	r := NewMT940Reader(strings.NewReader(testMT940))
+	var err error
+	for err == nil {
+		_, err = r.Next()
+	}
+	if got, want := err, ErrMalformedRows; got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+package register
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CleanTag trims the given tag and returns an error if nothing is left.
+func CleanTag(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return "", fmt.Errorf("tag must not be empty")
+	}
+	return tag, nil
+}
+
+// HasTag returns true if this Transaction has the given tag.
+func (t *Transaction) HasTag(tag string) bool {
+	i := sort.SearchStrings(t.Tags, tag)
+	return i < len(t.Tags) && t.Tags[i] == tag
+}
+
+// AddTag adds a tag to this Transaction, returning true if it did not already have it.
+func (t *Transaction) AddTag(tag string) bool {
+	i := sort.SearchStrings(t.Tags, tag)
+	if i < len(t.Tags) && t.Tags[i] == tag {
+		return false
+	}
+	t.Tags = append(t.Tags, "")
+	copy(t.Tags[i+1:], t.Tags[i:])
+	t.Tags[i] = tag
+	return true
+}
+
+// RemoveTag removes a tag from this Transaction, returning true if it had it.
+func (t *Transaction) RemoveTag(tag string) bool {
+	i := sort.SearchStrings(t.Tags, tag)
+	if i == len(t.Tags) || t.Tags[i] != tag {
+		return false
+	}
+	t.Tags = append(t.Tags[:i], t.Tags[i+1:]...)
+	if len(t.Tags) == 0 {
+		t.Tags = nil
+	}
+	return true
+}
+
+// FilterTags returns the Transactions that have every one of the given tags.
+func FilterTags(trans []*Transaction, tags []string) []*Transaction {
+	var res []*Transaction
+	for _, t := range trans {
+		match := true
+		for _, tag := range tags {
+			match = match && t.HasTag(tag)
+		}
+		if match {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// TagTotal is the number and summed amount of all Transactions with a tag.
+type TagTotal struct {
+	Tag    string  `json:"tag"`
+	Count  int     `json:"count"`
+	Amount float64 `json:"amount"`
+}
+
+// TagTotals returns the total of every tag used by the given Transactions, sorted by tag. Linked
+// transfers are not counted.
+func TagTotals(trans []*Transaction) []*TagTotal {
+	totals := make(map[string]*TagTotal)
+	for _, t := range trans {
+		if t.IsTransfer() {
+			continue
+		}
+		for _, tag := range t.Tags {
+			total, ok := totals[tag]
+			if !ok {
+				total = &TagTotal{Tag: tag}
+				totals[tag] = total
+			}
+			total.Count++
+			total.Amount += t.Amount
+		}
+	}
+	var res []*TagTotal
+	for _, total := range totals {
+		res = append(res, total)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Tag < res[j].Tag
+	})
+	return res
+}
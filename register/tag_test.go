@@ -0,0 +1,52 @@
+package register
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransactionTags(t *testing.T) {
+	trans := &Transaction{}
+	if got, want := trans.AddTag("vacation"), true; got != want {
+		t.Errorf("AddTag(vacation): got: %t, want: %t", got, want)
+	}
+	if got, want := trans.AddTag("reimbursable"), true; got != want {
+		t.Errorf("AddTag(reimbursable): got: %t, want: %t", got, want)
+	}
+	if got, want := trans.AddTag("vacation"), false; got != want {
+		t.Errorf("AddTag(vacation) again: got: %t, want: %t", got, want)
+	}
+	if got, want := trans.Tags, []string{"reimbursable", "vacation"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tags: got: %v, want: %v", got, want)
+	}
+	if !trans.HasTag("vacation") || trans.HasTag("tax") {
+		t.Errorf("HasTag: unexpected result for tags %v", trans.Tags)
+	}
+	if got, want := trans.RemoveTag("tax"), false; got != want {
+		t.Errorf("RemoveTag(tax): got: %t, want: %t", got, want)
+	}
+	if got, want := trans.RemoveTag("vacation"), true; got != want {
+		t.Errorf("RemoveTag(vacation): got: %t, want: %t", got, want)
+	}
+	if got, want := trans.Tags, []string{"reimbursable"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tags: got: %v, want: %v", got, want)
+	}
+}
+
+func TestTagTotals(t *testing.T) {
+	a := &Transaction{ID: "a", Amount: -10, Tags: []string{"tax", "vacation"}}
+	b := &Transaction{ID: "b", Amount: -5, Tags: []string{"vacation"}}
+	c := &Transaction{ID: "c", Amount: -1}
+	trans := []*Transaction{a, b, c}
+
+	want := []*TagTotal{
+		{Tag: "tax", Count: 1, Amount: -10},
+		{Tag: "vacation", Count: 2, Amount: -15},
+	}
+	if got := TagTotals(trans); !reflect.DeepEqual(got, want) {
+		t.Errorf("TagTotals: got: %v, want: %v", got, want)
+	}
+	if got, want := FilterTags(trans, []string{"vacation", "tax"}), []*Transaction{a}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterTags: got: %v, want: %v", got, want)
+	}
+}
@@ -2,20 +2,26 @@ package register
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
-	"strconv"
 	"strings"
 	"time"
 )
 
 // Transaction is a single financial transaction mapped to zero or more Categories.
 type Transaction struct {
-	ID          string      `json:"id"`
-	Description string      `json:"description"`
-	Amount      float64     `json:"amount"`
-	Date        *time.Time  `json:"date"`
-	Category    []*Category `json:"categories"`
+	ID          string        `json:"id"`
+	Description string        `json:"description"`
+	Payee       string        `json:"payee"`
+	Amount      float64       `json:"amount"`
+	Date        *time.Time    `json:"date"`
+	Category    []*Category   `json:"categories"`
+	Tags        []string      `json:"tags"`
+	Notes       string        `json:"notes"`
+	Attachments []*Attachment `json:"attachments"`
+	Account     string        `json:"account"`
+	TransferID  string        `json:"transferId"`
 }
 
 // String returns a quick representation of this Transaction.
@@ -23,40 +29,272 @@ func (t *Transaction) String() string {
 	return fmt.Sprintf("%s,%s,$%f", t.Date, t.Description, t.Amount)
 }
 
-// ReadAllTransactions imports all transactions from a CSV file until EOF. An error will be
-// returned if any error is encountered while reading or parsing.
-func ReadAllTransactions(r io.Reader) ([]*Transaction, error) {
+// RowError describes a single CSV row that could not be parsed. Row is the line number in the
+// file, counting the header as line 1.
+type RowError struct {
+	Row    int    `json:"row"`
+	Column string `json:"column"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// Error returns a quick representation of this RowError.
+func (e *RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("row %d: %s", e.Row, e.Reason)
+	}
+	return fmt.Sprintf("row %d, column %s: %q: %s", e.Row, e.Column, e.Value, e.Reason)
+}
+
+// The columns of a bank CSV export. The amount is split into debit and credit columns, one of
+// which is empty.
+const (
+	dateColumn        = 0
+	descriptionColumn = 2
+	debitColumn       = 3
+	creditColumn      = 4
+)
+
+func (f *CSVFormat) parseRecord(row int, record []string) (*Transaction, *RowError) {
+	if len(record) <= creditColumn {
+		return nil, &RowError{Row: row, Reason: fmt.Sprintf("want at least %d fields, got %d", creditColumn+1, len(record))}
+	}
+	date, err := time.Parse(f.DateLayout, strings.TrimSpace(record[dateColumn]))
+	if err != nil {
+		return nil, &RowError{Row: row, Column: "date", Value: record[dateColumn], Reason: fmt.Sprintf("date must look like %s", f.DateLayout)}
+	}
+	desc := record[descriptionColumn]
+	amountStr := strings.TrimSpace(record[debitColumn] + record[creditColumn])
+	amount, err := f.ParseAmount(amountStr)
+	if err != nil {
+		return nil, &RowError{Row: row, Column: "amount", Value: amountStr, Reason: "amount must be a number in exactly one of the debit and credit columns"}
+	}
+	return &Transaction{
+		ID:          statementID("", "", date, desc, amount),
+		Description: desc,
+		Amount:      amount,
+		Date:        &date,
+	}, nil
+}
+
+// ErrMalformedRows is returned by a strict statement reader, in place of io.EOF, when any row of
+// the file could not be parsed.
+var ErrMalformedRows = errors.New("file has malformed rows")
+
+// maxRowErrors is how many RowErrors a statement reader keeps. Rows skipped after that are only
+// counted, so that a file of garbage does not fill memory.
+const maxRowErrors = 100
+
+// rowErrors collects the rows skipped by a statement reader: the first maxRowErrors RowErrors and
+// the number of rows skipped in all.
+type rowErrors struct {
+	errs  []*RowError
+	count int
+}
+
+func (e *rowErrors) add(rowErr *RowError) {
+	e.count++
+	if len(e.errs) < maxRowErrors {
+		e.errs = append(e.errs, rowErr)
+	}
+}
+
+// endOfRows returns the error a statement reader ends with: ErrMalformedRows if it is strict and
+// skipped any rows, or io.EOF.
+func endOfRows(lenient bool, rowErrs *rowErrors) error {
+	if rowErrs.count > 0 && !lenient {
+		return ErrMalformedRows
+	}
+	return io.EOF
+}
+
+// statementID is the ID of a Transaction from a bank statement. The bank's reference for the
+// Transaction is used when there is one, since it is stable across exports.
+func statementID(account, ref string, date time.Time, desc string, amount float64) string {
+	if ref != "" && !strings.EqualFold(ref, "NONREF") {
+		return fmt.Sprintf("REF-%s-%s", account, ref)
+	}
+	return fmt.Sprintf("TRANS-%s-%s-%f", date, desc, amount)
+}
+
+// Source is a stream of Transactions. Next returns io.EOF after the last Transaction.
+type Source interface {
+	Next() (*Transaction, error)
+}
+
+// sliceSource is a Source over Transactions that are already in memory.
+type sliceSource struct {
+	trans []*Transaction
+}
+
+func (s *sliceSource) Next() (*Transaction, error) {
+	if len(s.trans) == 0 {
+		return nil, io.EOF
+	}
+	t := s.trans[0]
+	s.trans = s.trans[1:]
+	return t, nil
+}
+
+// SliceSource returns a Source that yields the given Transactions in order.
+func SliceSource(trans []*Transaction) Source {
+	return &sliceSource{trans: trans}
+}
+
+// sampledRow is a row read ahead of time to detect the format of a file.
+type sampledRow struct {
+	row    int
+	record []string
+	err    *RowError
+}
+
+// Reader reads Transactions from a CSV file one row at a time, so that memory use does not grow
+// with the size of the file. Rows that cannot be parsed are skipped and collected as RowErrors, up
+// to maxRowErrors of them. Unless Lenient is set, Next returns ErrMalformedRows instead of io.EOF
+// if any row was skipped.
+type Reader struct {
+	Lenient bool
+
+	csv     *csv.Reader
+	format  CSVFormat
+	sampled []*sampledRow
+	rowErrs rowErrors
+}
+
+// NewReader returns a Reader for the given CSV file, after reading its header row. The format of
+// dates and amounts is detected from the first rows of the file, except for the non-empty fields
+// of override. While the dates read as well day first as month first, more rows are sampled until
+// one tells them apart, and an error is returned if none does.
+func NewReader(r io.Reader, override CSVFormat) (*Reader, error) {
 	reader := csv.NewReader(r)
-	var trans []*Transaction
+	reader.FieldsPerRecord = -1
 	// Drop header row.
 	if _, err := reader.Read(); err != nil {
 		return nil, fmt.Errorf("csv.Reader.Read: %v", err)
 	}
-	for {
+
+	rdr := &Reader{csv: reader}
+	var dates, amounts []string
+	for len(rdr.sampled) < maxSampleRows {
+		if n := len(rdr.sampled); n >= sampleRows && n%sampleRows == 0 {
+			if _, ok := detectDateLayout(dates); ok || override.DateLayout != "" {
+				break
+			}
+		}
 		record, err := reader.Read()
 		if err == io.EOF {
 			break
 		}
+		if pe, ok := err.(*csv.ParseError); ok {
+			rdr.sampled = append(rdr.sampled, &sampledRow{err: &RowError{Row: pe.StartLine, Reason: pe.Err.Error()}})
+			continue
+		}
 		if err != nil {
 			return nil, fmt.Errorf("csv.Reader.Read: %v", err)
 		}
-		date, err := time.Parse("1/2/2006", record[0])
-		if err != nil {
-			return nil, fmt.Errorf("time.Parse(%s): %v", record[0], err)
+		row, _ := reader.FieldPos(0)
+		rdr.sampled = append(rdr.sampled, &sampledRow{row: row, record: record})
+		if len(record) > creditColumn {
+			dates = append(dates, record[dateColumn])
+			amounts = append(amounts, record[debitColumn], record[creditColumn])
+		}
+	}
+	reader.ReuseRecord = true
+
+	format, err := DetectCSVFormat(dates, amounts, override)
+	if err != nil {
+		return nil, err
+	}
+	rdr.format = format
+	if err := rdr.format.Validate(); err != nil {
+		return nil, err
+	}
+	return rdr, nil
+}
+
+// Format returns the format this Reader parses dates and amounts with.
+func (r *Reader) Format() CSVFormat {
+	return r.format
+}
+
+func (r *Reader) read() (int, []string, *RowError, error) {
+	if len(r.sampled) > 0 {
+		s := r.sampled[0]
+		r.sampled = r.sampled[1:]
+		return s.row, s.record, s.err, nil
+	}
+	record, err := r.csv.Read()
+	if pe, ok := err.(*csv.ParseError); ok {
+		return 0, nil, &RowError{Row: pe.StartLine, Reason: pe.Err.Error()}, nil
+	}
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	row, _ := r.csv.FieldPos(0)
+	return row, record, nil, nil
+}
+
+// Next returns the next Transaction in the file.
+func (r *Reader) Next() (*Transaction, error) {
+	for {
+		row, record, rowErr, err := r.read()
+		if err == io.EOF {
+			return nil, endOfRows(r.Lenient, &r.rowErrs)
 		}
-		desc := record[2]
-		amountStr := strings.TrimSpace(record[3] + record[4])
-		amount, err := strconv.ParseFloat(amountStr, 64)
 		if err != nil {
-			return nil, fmt.Errorf("ParseFloat(%s): %v", amountStr, err)
+			return nil, fmt.Errorf("csv.Reader.Read: %v", err)
 		}
-		t := &Transaction{
-			ID:          fmt.Sprintf("TRANS-%s-%s-%f", date, desc, amount),
-			Description: desc,
-			Amount:      amount,
-			Date:        &date,
+		if rowErr == nil {
+			var t *Transaction
+			if t, rowErr = r.format.parseRecord(row, record); rowErr == nil {
+				return t, nil
+			}
+		}
+		r.rowErrs.add(rowErr)
+	}
+}
+
+// RowErrors returns the first maxRowErrors rows skipped so far.
+func (r *Reader) RowErrors() []*RowError {
+	return r.rowErrs.errs
+}
+
+// SkippedRows returns the number of rows skipped so far, including those not in RowErrors.
+func (r *Reader) SkippedRows() int {
+	return r.rowErrs.count
+}
+
+// ReadTransactions imports all transactions from a CSV file until EOF. Rows that cannot be parsed
+// are skipped and reported as RowErrors. An error is only returned if the file cannot be read.
+func ReadTransactions(r io.Reader) ([]*Transaction, []*RowError, error) {
+	reader, err := NewReader(r, CSVFormat{})
+	if err != nil {
+		return nil, nil, err
+	}
+	reader.Lenient = true
+	var trans []*Transaction
+	for {
+		t, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
 		}
 		trans = append(trans, t)
 	}
+	return trans, reader.RowErrors(), nil
+}
+
+// ReadAllTransactions imports all transactions from a CSV file until EOF. An error will be
+// returned if any error is encountered while reading or parsing.
+func ReadAllTransactions(r io.Reader) ([]*Transaction, error) {
+	trans, rowErrs, err := ReadTransactions(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(rowErrs) > 0 {
+		return nil, rowErrs[0]
+	}
 	return trans, nil
 }
@@ -0,0 +1,157 @@
+package register
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testCSV = `Date,No.,Description,Debit,Credit
+5/1/2018,,COFFEE,-5.50,
+5/32/2018,,BAD DATE,-1.00,
+5/20/2018,,PAYCHECK,,1000
+5/3/2018,,NO AMOUNT,,
+5/4/2018,,SHORT
+`
+
+func TestReadTransactions(t *testing.T) {
+	trans, rowErrs, err := ReadTransactions(strings.NewReader(testCSV))
+	if err != nil {
+		t.Fatalf("ReadTransactions: %v", err)
+	}
+	var descs []string
+	for _, t := range trans {
+		descs = append(descs, t.Description)
+	}
+	if got, want := descs, []string{"COFFEE", "PAYCHECK"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("transactions: got: %v, want: %v", got, want)
+	}
+
+	want := []*RowError{
+		{Row: 3, Column: "date", Value: "5/32/2018", Reason: "date must look like 1/2/2006"},
+		{Row: 5, Column: "amount", Value: "", Reason: "amount must be a number in exactly one of the debit and credit columns"},
+		{Row: 6, Reason: "want at least 5 fields, got 3"},
+	}
+	if got := rowErrs; !reflect.DeepEqual(got, want) {
+		t.Errorf("row errors: got: %v, want: %v", got, want)
+	}
+
+	if _, err := ReadAllTransactions(strings.NewReader(testCSV)); err == nil {
+		t.Error("ReadAllTransactions: expected non-nil error for malformed rows")
+	}
+}
+
+func TestReaderStrict(t *testing.T) {
+	r, err := NewReader(strings.NewReader(testCSV), CSVFormat{})
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	var count int
+	for {
+		_, err := r.Next()
+		if err == io.EOF {
+			t.Fatal("Next: got: io.EOF, want: ErrMalformedRows")
+		}
+		if err == ErrMalformedRows {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		count++
+	}
+	if got, want := count, 2; got != want {
+		t.Errorf("transactions: got: %d, want: %d", got, want)
+	}
+	if got, want := len(r.RowErrors()), 3; got != want {
+		t.Errorf("row errors: got: %d, want: %d", got, want)
+	}
+}
+
+func TestReaderDetectsFormat(t *testing.T) {
+	const europeanCSV = "Datum,Nr.,Beschreibung,Soll,Haben\n01.05.2018,,KAFFEE,\"-1.234,50\",\n13.05.2018,,GEHALT,,\"2.000,00\"\n"
+	r, err := NewReader(strings.NewReader(europeanCSV), CSVFormat{})
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	if got, want := r.Format(), (CSVFormat{DateLayout: "2.1.2006", Decimal: ",", Thousands: ".", Negative: NegativeMinus}); got != want {
+		t.Errorf("format: got: %+v, want: %+v", got, want)
+	}
+	var amounts []float64
+	for {
+		trans, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		amounts = append(amounts, trans.Amount)
+	}
+	if got, want := amounts, []float64{-1234.5, 2000}; !reflect.DeepEqual(got, want) {
+		t.Errorf("amounts: got: %v, want: %v", got, want)
+	}
+}
+
+func TestReaderSamplesAmbiguousDates(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("Date,No.,Description,Debit,Credit\n")
+	for i := 0; i < 2*sampleRows; i++ {
+		b.WriteString("3/4/2018,,COFFEE,-5.50,\n")
+	}
+	ambiguous := b.String()
+	if _, err := NewReader(strings.NewReader(ambiguous), CSVFormat{}); err == nil {
+		t.Error("NewReader: expected non-nil error for dates that are day or month first")
+	}
+
+	r, err := NewReader(strings.NewReader(ambiguous+"3/25/2018,,COFFEE,-5.50,\n"), CSVFormat{})
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	if got, want := r.Format().DateLayout, "1/2/2006"; got != want {
+		t.Errorf("date layout: got: %s, want: %s", got, want)
+	}
+	var count int
+	for {
+		_, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		count++
+	}
+	if got, want := count, 2*sampleRows+1; got != want {
+		t.Errorf("transactions: got: %d, want: %d", got, want)
+	}
+}
+
+func TestReaderCapsRowErrors(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("Date,No.,Description,Debit,Credit\n5/21/2018,,COFFEE,-5.50,\n")
+	for i := 0; i < maxRowErrors+10; i++ {
+		b.WriteString("5/21/2018,,SHORT\n")
+	}
+	r, err := NewReader(strings.NewReader(b.String()), CSVFormat{})
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	r.Lenient = true
+	for {
+		_, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+	}
+	if got, want := len(r.RowErrors()), maxRowErrors; got != want {
+		t.Errorf("row errors: got: %d, want: %d", got, want)
+	}
+	if got, want := r.SkippedRows(), maxRowErrors+10; got != want {
+		t.Errorf("skipped rows: got: %d, want: %d", got, want)
+	}
+}
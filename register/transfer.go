@@ -0,0 +1,135 @@
+package register
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// DefaultTransferDays is the default number of days two halves of a transfer may be apart.
+const DefaultTransferDays = 3
+
+var transferRE = regexp.MustCompile(`(?i)\b(transfer|xfer|trnsfr|tfr|to savings|from savings|to checking|from checking|online banking|payment thank you|autopay)\b`)
+
+// TransferPair is a pair of Transactions that move money between two accounts. From is the
+// outgoing side and To is the incoming side.
+type TransferPair struct {
+	From *Transaction `json:"from"`
+	To   *Transaction `json:"to"`
+}
+
+// IsTransfer returns true if this Transaction has been linked to the other half of a transfer.
+// Linked transfers are neither spending nor income.
+func (t *Transaction) IsTransfer() bool {
+	return t.TransferID != ""
+}
+
+// TransferLike returns true if the given description reads like a transfer between accounts.
+func TransferLike(desc string) bool {
+	return transferRE.MatchString(desc)
+}
+
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func daysApart(a, b *time.Time) int {
+	d := a.Sub(*b)
+	if d < 0 {
+		d = -d
+	}
+	return int(d.Hours() / 24)
+}
+
+func transferCandidates(from, to *Transaction, days int) bool {
+	if from.IsTransfer() || to.IsTransfer() || from.Date == nil || to.Date == nil {
+		return false
+	}
+	if from.Amount >= 0 || cents(from.Amount) != -cents(to.Amount) {
+		return false
+	}
+	if from.Account != "" && from.Account == to.Account {
+		return false
+	}
+	if daysApart(from.Date, to.Date) > days {
+		return false
+	}
+	return TransferLike(from.Description) || TransferLike(to.Description)
+}
+
+// FindTransfers suggests pairs of unlinked Transactions that look like transfers: opposite-signed,
+// equal amounts, at most the given number of days apart, and a transfer-like description on at
+// least one side. Each Transaction is used in at most one pair, pairing the closest dates first.
+func FindTransfers(trans []*Transaction, days int) []*TransferPair {
+	var outs, ins []*Transaction
+	for _, t := range trans {
+		switch {
+		case t.Amount < 0:
+			outs = append(outs, t)
+		case t.Amount > 0:
+			ins = append(ins, t)
+		}
+	}
+	type candidate struct {
+		from, to *Transaction
+		days     int
+	}
+	var cands []*candidate
+	for _, from := range outs {
+		for _, to := range ins {
+			if transferCandidates(from, to, days) {
+				cands = append(cands, &candidate{from: from, to: to, days: daysApart(from.Date, to.Date)})
+			}
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		return cands[i].days < cands[j].days
+	})
+
+	used := make(map[*Transaction]bool)
+	var pairs []*TransferPair
+	for _, c := range cands {
+		if used[c.from] || used[c.to] {
+			continue
+		}
+		used[c.from] = true
+		used[c.to] = true
+		pairs = append(pairs, &TransferPair{From: c.from, To: c.to})
+	}
+	return pairs
+}
+
+// LinkTransfer marks the two given Transactions as the halves of a single transfer.
+func LinkTransfer(a, b *Transaction) error {
+	if a == b {
+		return fmt.Errorf("transaction %s cannot be a transfer to itself", a.ID)
+	}
+	if a.IsTransfer() || b.IsTransfer() {
+		return fmt.Errorf("transactions %s and %s must not already be linked", a.ID, b.ID)
+	}
+	if a.Amount == 0 || cents(a.Amount) != -cents(b.Amount) {
+		return fmt.Errorf("transactions %s and %s must have opposite, equal amounts", a.ID, b.ID)
+	}
+	a.TransferID = b.ID
+	b.TransferID = a.ID
+	return nil
+}
+
+// LinkedTransfers returns every confirmed TransferPair among the given Transactions.
+func LinkedTransfers(trans []*Transaction) []*TransferPair {
+	byID := make(map[string]*Transaction)
+	for _, t := range trans {
+		byID[t.ID] = t
+	}
+	var pairs []*TransferPair
+	for _, t := range trans {
+		other, ok := byID[t.TransferID]
+		if !ok || t.Amount >= 0 {
+			continue
+		}
+		pairs = append(pairs, &TransferPair{From: t, To: other})
+	}
+	return pairs
+}
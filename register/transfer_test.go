@@ -0,0 +1,105 @@
+package register
+
+import (
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) *time.Time {
+	t := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestFindTransfers(t *testing.T) {
+	var (
+		out     = &Transaction{ID: "out", Description: "ONLINE TRANSFER TO SAVINGS", Amount: -100, Date: date(2018, time.May, 1), Account: "checking"}
+		in      = &Transaction{ID: "in", Description: "DEPOSIT", Amount: 100, Date: date(2018, time.May, 2), Account: "savings"}
+		late    = &Transaction{ID: "late", Description: "TRANSFER FROM CHECKING", Amount: 100, Date: date(2018, time.May, 20)}
+		refund  = &Transaction{ID: "refund", Description: "STORE REFUND", Amount: 100, Date: date(2018, time.May, 1)}
+		same    = &Transaction{ID: "same", Description: "TRANSFER", Amount: 100, Date: date(2018, time.May, 1), Account: "checking"}
+		buy     = &Transaction{ID: "buy", Description: "STORE", Amount: -100, Date: date(2018, time.May, 1)}
+		otherIn = &Transaction{ID: "other", Description: "TRANSFER", Amount: 99.99, Date: date(2018, time.May, 1)}
+	)
+
+	tests := []struct {
+		label string
+		trans []*Transaction
+		want  map[string]string
+	}{
+		{
+			label: "simple pair",
+			trans: []*Transaction{out, in},
+			want:  map[string]string{"out": "in"},
+		},
+		{
+			label: "too far apart",
+			trans: []*Transaction{out, late},
+			want:  map[string]string{},
+		},
+		{
+			label: "closest date wins",
+			trans: []*Transaction{out, late, refund, in},
+			want:  map[string]string{"out": "refund"},
+		},
+		{
+			label: "same account",
+			trans: []*Transaction{out, same},
+			want:  map[string]string{},
+		},
+		{
+			label: "no transfer-like description",
+			trans: []*Transaction{buy, refund},
+			want:  map[string]string{},
+		},
+		{
+			label: "amounts differ",
+			trans: []*Transaction{out, otherIn},
+			want:  map[string]string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			pairs := FindTransfers(test.trans, DefaultTransferDays)
+			got := make(map[string]string)
+			for _, p := range pairs {
+				got[p.From.ID] = p.To.ID
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("pairs: got: %v, want: %v", got, test.want)
+			}
+			for from, to := range test.want {
+				if got[from] != to {
+					t.Errorf("pair for %s: got: %s, want: %s", from, got[from], to)
+				}
+			}
+		})
+	}
+}
+
+func TestLinkTransfer(t *testing.T) {
+	out := &Transaction{ID: "out", Amount: -100}
+	in := &Transaction{ID: "in", Amount: 100, Category: []*Category{{Name: "Income", Amount: 100}}}
+	bad := &Transaction{ID: "bad", Amount: 50}
+	if err := LinkTransfer(out, bad); err == nil {
+		t.Error("expected non-nil error linking different amounts")
+	}
+	if err := LinkTransfer(out, in); err != nil {
+		t.Fatalf("LinkTransfer: %v", err)
+	}
+	if err := LinkTransfer(out, in); err == nil {
+		t.Error("expected non-nil error linking an already linked pair")
+	}
+	pairs := LinkedTransfers([]*Transaction{out, in, bad})
+	if got, want := len(pairs), 1; got != want {
+		t.Fatalf("linked pairs: got: %d, want: %d", got, want)
+	}
+	if pairs[0].From != out || pairs[0].To != in {
+		t.Errorf("linked pair: got: %v -> %v, want: %v -> %v", pairs[0].From, pairs[0].To, out, in)
+	}
+
+	ct := NewCategoryTree("Income")
+	if got, want := ct.RollUp([]*Transaction{in})[0].Total, 0.0; got != want {
+		t.Errorf("linked transfer should not be rolled up: got: %f, want: %f", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package rule
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/groggygopher/oyster/register"
+)
+
+// Example is a sample Transaction that travels with a Rule, along with whether the Rule is
+// expected to match it. Examples protect a Rule from regressions as it is edited.
+type Example struct {
+	Transaction *register.Transaction `json:"transaction"`
+	Match       bool                  `json:"match"`
+}
+
+// ExampleFailure describes a single Example whose expectation was not met by its Rule.
+type ExampleFailure struct {
+	Rule    string   `json:"rule"`
+	Index   int      `json:"index"`
+	Example *Example `json:"example"`
+}
+
+// String returns a quick representation of this ExampleFailure.
+func (f *ExampleFailure) String() string {
+	if f.Example == nil || f.Example.Transaction == nil {
+		return fmt.Sprintf("rule '%s' example %d: has no transaction", f.Rule, f.Index)
+	}
+	want := "match"
+	if !f.Example.Match {
+		want = "no match"
+	}
+	return fmt.Sprintf("rule '%s' example %d: want %s for %v", f.Rule, f.Index, want, f.Example.Transaction)
+}
+
+// ExamplesError is returned when a Rule does not satisfy its own Examples.
+type ExamplesError struct {
+	Failures []*ExampleFailure
+}
+
+// Error lists every failed Example.
+func (e *ExamplesError) Error() string {
+	var fs []string
+	for _, f := range e.Failures {
+		fs = append(fs, f.String())
+	}
+	return fmt.Sprintf("%d failed examples: %s", len(e.Failures), strings.Join(fs, "; "))
+}
+
+// CheckExamples evaluates this Rule against each of its Examples, and every nested And and Or Rule
+// against theirs, and returns the ones that did not produce the expected result. Examples without
+// a Transaction always fail. Failures of a nested Rule without a name are reported under the name
+// of the Rule it is nested in.
+func (r *Rule) CheckExamples() []*ExampleFailure {
+	return r.checkExamples(r.Name)
+}
+
+func (r *Rule) checkExamples(name string) []*ExampleFailure {
+	if r.Name != "" {
+		name = r.Name
+	}
+	var failures []*ExampleFailure
+	for i, ex := range r.Examples {
+		if ex == nil || ex.Transaction == nil || r.Evaluate(ex.Transaction) != ex.Match {
+			failures = append(failures, &ExampleFailure{Rule: name, Index: i, Example: ex})
+		}
+	}
+	for _, sub := range append(append([]*Rule(nil), r.And...), r.Or...) {
+		if sub != nil {
+			failures = append(failures, sub.checkExamples(name)...)
+		}
+	}
+	return failures
+}
+
+// Validate returns an *ExamplesError if this Rule fails any of its own Examples.
+func (r *Rule) Validate() error {
+	if failures := r.CheckExamples(); len(failures) > 0 {
+		return &ExamplesError{Failures: failures}
+	}
+	return nil
+}
+
+// RunExamples runs the Examples of every given Rule and returns all of the failures.
+func RunExamples(rs []*Rule) []*ExampleFailure {
+	var failures []*ExampleFailure
+	for _, r := range rs {
+		failures = append(failures, r.CheckExamples()...)
+	}
+	return failures
+}
@@ -0,0 +1,151 @@
+package rule
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/groggygopher/oyster/register"
+)
+
+func TestCheckExamples(t *testing.T) {
+	match := &register.Transaction{Description: "coffee shop", Amount: -4.5}
+	noMatch := &register.Transaction{Description: "grocery store", Amount: -54.1}
+
+	tests := []struct {
+		label        string
+		examples     []*Example
+		wantFailures int
+	}{
+		{
+			label: "no examples",
+		},
+		{
+			label: "all pass",
+			examples: []*Example{
+				{Transaction: match, Match: true},
+				{Transaction: noMatch, Match: false},
+			},
+		},
+		{
+			label: "unexpected match",
+			examples: []*Example{
+				{Transaction: match, Match: false},
+				{Transaction: noMatch, Match: false},
+			},
+			wantFailures: 1,
+		},
+		{
+			label: "all fail",
+			examples: []*Example{
+				{Transaction: match, Match: false},
+				{Transaction: noMatch, Match: true},
+			},
+			wantFailures: 2,
+		},
+		{
+			label: "missing transaction",
+			examples: []*Example{
+				{Match: true},
+			},
+			wantFailures: 1,
+		},
+		{
+			label:        "nil example",
+			examples:     []*Example{nil},
+			wantFailures: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			r := &Rule{
+				Name:        "coffee",
+				Description: &Description{regexp.MustCompile("coffee")},
+				Examples:    test.examples,
+			}
+			if got, want := len(r.CheckExamples()), test.wantFailures; got != want {
+				t.Errorf("failures: got: %d, want: %d", got, want)
+			}
+			if got, want := r.Validate() != nil, test.wantFailures > 0; got != want {
+				t.Errorf("Validate error: got: %t, want: %t", got, want)
+			}
+		})
+	}
+}
+
+func TestCheckNestedExamples(t *testing.T) {
+	r := &Rule{
+		Name: "coffee",
+		Or: []*Rule{
+			{
+				Description: &Description{regexp.MustCompile("coffee")},
+				Examples: []*Example{
+					{Transaction: &register.Transaction{Description: "coffee shop"}, Match: false},
+					nil,
+				},
+			},
+		},
+	}
+	failures := r.CheckExamples()
+	if got, want := len(failures), 2; got != want {
+		t.Fatalf("failures: got: %d, want: %d", got, want)
+	}
+	if got, want := failures[0].Rule, "coffee"; got != want {
+		t.Errorf("rule: got: %s, want: %s", got, want)
+	}
+	if failures[1].Example != nil {
+		t.Errorf("a nil example should be reported as nil, got: %v", failures[1].Example)
+	}
+	if got := failures[1].String(); got == "" {
+		t.Error("String: got empty failure description")
+	}
+}
+
+func TestRunExamples(t *testing.T) {
+	trans := &register.Transaction{Description: "test"}
+	rs := []*Rule{
+		{
+			Name:        "pass",
+			Description: &Description{regexp.MustCompile("test")},
+			Examples:    []*Example{{Transaction: trans, Match: true}},
+		},
+		{
+			Name:        "fail",
+			Description: &Description{regexp.MustCompile("bad")},
+			Examples:    []*Example{{Transaction: trans, Match: true}},
+		},
+	}
+	failures := RunExamples(rs)
+	if got, want := len(failures), 1; got != want {
+		t.Fatalf("failures: got: %d, want: %d", got, want)
+	}
+	if got, want := failures[0].Rule, "fail"; got != want {
+		t.Errorf("failed rule: got: %s, want: %s", got, want)
+	}
+}
+
+func TestUpsertRuleExamples(t *testing.T) {
+	trans := &register.Transaction{Description: "test"}
+	m := NewEmptyManager()
+	good := &Rule{
+		Name:        "test",
+		Description: &Description{regexp.MustCompile("test")},
+		Examples:    []*Example{{Transaction: trans, Match: true}},
+	}
+	if err := m.UpsertRule(good.Name, good); err != nil {
+		t.Fatalf("UpsertRule(good): %v", err)
+	}
+
+	bad := &Rule{
+		Name:        "test",
+		Description: &Description{regexp.MustCompile("bad")},
+		Examples:    []*Example{{Transaction: trans, Match: true}},
+	}
+	err := m.UpsertRule(bad.Name, bad)
+	if _, ok := err.(*ExamplesError); !ok {
+		t.Fatalf("UpsertRule(bad): got: %v, want: *ExamplesError", err)
+	}
+	if got, want := m.Rules()[0], good; got != want {
+		t.Errorf("rejected upsert replaced the rule: got: %v, want: %v", got, want)
+	}
+}
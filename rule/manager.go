@@ -47,7 +47,10 @@ func (m *Manager) Rules() []*Rule {
 }
 
 // AddRule adds a rule to this Manager, returning true if anything was added.
-// Use UpsertRule to modify a rule.
+// Use UpsertRule to modify a rule. Unlike UpsertRule, AddRule does not check
+// the Rule's Examples, so that rules loaded from a save file are kept even if
+// they have come to fail them; RunExamples reports those. Callers adding a new
+// Rule should Validate it first.
 func (m *Manager) AddRule(r *Rule) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -58,11 +61,16 @@ func (m *Manager) AddRule(r *Rule) bool {
 	return true
 }
 
-// UpsertRule adds a rule to this Manager, overriding any previous Rules with the same name.
-func (m *Manager) UpsertRule(name string, r *Rule) {
+// UpsertRule adds a rule to this Manager, overriding any previous Rules with the same name. The
+// Rule is rejected with an *ExamplesError if it fails any of its own Examples.
+func (m *Manager) UpsertRule(name string, r *Rule) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.rules[r.Name] = r
+	return nil
 }
 
 // DeleteRule deletes a rule from this Manager, returning true if anything was removed.
@@ -76,6 +84,26 @@ func (m *Manager) DeleteRule(n string) bool {
 	return true
 }
 
+// CheckExamples runs the Examples of every rule in this Manager and returns all of the failures.
+func (m *Manager) CheckExamples() []*ExampleFailure {
+	return RunExamples(m.Rules())
+}
+
+// MoveCategory rewrites the Category of every rule that sets old, or one of its descendants, so
+// that it lives under new instead. The number of modified rules is returned.
+func (m *Manager) MoveCategory(old, new string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var count int
+	for _, r := range m.rules {
+		if moved, ok := register.MoveCategory(r.Category, old, new); ok {
+			r.Category = moved
+			count++
+		}
+	}
+	return count
+}
+
 // LoadRules deserializes all the rules in the given Reader and adds them to this Manager. If there
 // is any problem deserializing, no rules are added.
 func (m *Manager) LoadRules(r io.Reader) error {
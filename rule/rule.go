@@ -21,7 +21,8 @@ type AmountRange struct {
 	Max *float64 `json:"max"`
 }
 
-// Description is the Transaction description RE on which a Rule can be evaluated.
+// Description is a RE on which a Rule can evaluate a Transaction's raw description or its
+// normalized payee.
 type Description struct {
 	r *regexp.Regexp
 }
@@ -51,8 +52,11 @@ type Rule struct {
 	And           []*Rule      `json:"and"`
 	Or            []*Rule      `json:"or"`
 	Description   *Description `json:"description"`
+	Payee         *Description `json:"payee"`
 	DateBetween   *DateRange   `json:"dateBetween"`
 	AmountBetween *AmountRange `json:"amountBetween"`
+
+	Examples []*Example `json:"examples"`
 }
 
 // Evaluate checks if the Transaction matches this rule and returns true if so.
@@ -63,6 +67,10 @@ func (r *Rule) Evaluate(t *register.Transaction) bool {
 		set = true
 		local = local && r.Description.r.MatchString(t.Description)
 	}
+	if r.Payee != nil && t.Payee != "" {
+		set = true
+		local = local && r.Payee.r.MatchString(t.Payee)
+	}
 	if r.DateBetween != nil && t.Date != nil {
 		set = true
 		isBetween := true
@@ -25,12 +25,16 @@ var (
 	trans = &register.Transaction{
 		Date:        &now,
 		Description: "test",
+		Payee:       "Test",
 		Amount:      amount,
 	}
 
 	descriptionMatch   = &Rule{Description: &Description{r: regexp.MustCompile("test")}}
 	descriptionNoMatch = &Rule{Description: &Description{r: regexp.MustCompile("bad")}}
 
+	payeeMatch   = &Rule{Payee: &Description{r: regexp.MustCompile("^Test$")}}
+	payeeNoMatch = &Rule{Payee: &Description{r: regexp.MustCompile("^test$")}}
+
 	dateBeforeMatch   = &Rule{DateBetween: &DateRange{Before: &after}}
 	dateBeforeNoMatch = &Rule{DateBetween: &DateRange{Before: &before}}
 	dateAfterMatch    = &Rule{DateBetween: &DateRange{After: &before}}
@@ -58,6 +62,16 @@ func TestRuleEvaluate(t *testing.T) {
 			rule:  descriptionNoMatch,
 			want:  false,
 		},
+		{
+			label: "match payee",
+			rule:  payeeMatch,
+			want:  true,
+		},
+		{
+			label: "no match payee",
+			rule:  payeeNoMatch,
+			want:  false,
+		},
 		{
 			label: "match date before",
 			rule:  dateBeforeMatch,
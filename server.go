@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/groggygopher/oyster/handlers"
 	"github.com/groggygopher/oyster/session"
@@ -16,10 +20,16 @@ var (
 	// csvFile  = flag.String("csv_file", "", "The CSV file to import")
 	// ruleFile = flag.String("rule_file", "", "The JSON encoded rule file")
 
-	port    = flag.Int("port", 8080, "The port to serve HTTP on")
-	saveDir = flag.String("save_dir", filepath.Join(os.TempDir(), "oyster"), "The directory to save user data")
+	port      = flag.Int("port", 8080, "The port to serve HTTP on")
+	saveDir   = flag.String("save_dir", filepath.Join(os.TempDir(), "oyster"), "The directory to save user data")
+	snapshots = flag.Int("snapshots", session.DefaultSnapshots, "The number of snapshots of each user's data to keep")
+	tlsCert   = flag.String("tls_cert", "", "The TLS certificate file to serve HTTPS with, along with tls_key")
+	tlsKey    = flag.String("tls_key", "", "The TLS private key file of tls_cert")
 )
 
+// shutdownTimeout is how long in-flight requests have to finish on shutdown.
+const shutdownTimeout = 30 * time.Second
+
 func createSaveDir(saveDir string) error {
 	fInfo, err := os.Stat(saveDir)
 	if os.IsNotExist(err) {
@@ -35,23 +45,71 @@ func createSaveDir(saveDir string) error {
 }
 
 func main() {
+	flag.Parse()
+	if (*tlsCert == "") != (*tlsKey == "") {
+		log.Fatal("tls_cert and tls_key must be given together")
+	}
 	if err := createSaveDir(*saveDir); err != nil {
 		log.Fatalf("createSaveDir: %v", err)
 	}
-	sessMgr := session.NewManager(*saveDir)
+	sessMgr := session.NewManager(session.NewFileStore(*saveDir))
+	sessMgr.SetSnapshots(*snapshots, session.DefaultSnapshotInterval)
 	defer func() {
 		if err := sessMgr.Close(); err != nil {
 			log.Fatalf("error: sessionManager.Close: %v", err)
 		}
 	}()
 
+	http.Handle("/accounts", handlers.NewAccountHandler(sessMgr))
+	http.Handle("/attachments", handlers.NewAttachmentHandler(sessMgr))
+	http.Handle("/budgets", handlers.NewBudgetHandler(sessMgr))
+	http.Handle("/calendar.ics", handlers.NewCalendarHandler(sessMgr))
+	http.Handle("/categories", handlers.NewCategoryHandler(sessMgr))
+	http.Handle("/duplicates", handlers.NewDuplicateHandler(sessMgr))
+	http.Handle("/forecast", handlers.NewForecastHandler(sessMgr))
+	http.Handle("/imports", handlers.NewImportHandler(sessMgr))
+	http.Handle("/notes", handlers.NewNotesHandler(sessMgr))
+	http.Handle("/password", handlers.NewPasswordHandler(sessMgr))
+	http.Handle("/payees", handlers.NewPayeeHandler(sessMgr))
+	http.Handle("/recovery", handlers.NewRecoveryHandler(sessMgr))
+	http.Handle("/recurring", handlers.NewRecurringHandler(sessMgr))
 	http.Handle("/rules", handlers.NewRuleHandler(sessMgr))
+	http.Handle("/schedules", handlers.NewScheduleHandler(sessMgr))
 	http.Handle("/session", handlers.NewSessionHandler(sessMgr))
+	http.Handle("/sessions", handlers.NewSessionsHandler(sessMgr))
+	http.Handle("/snapshots", handlers.NewSnapshotHandler(sessMgr))
+	http.Handle("/tags", handlers.NewTagHandler(sessMgr))
 	http.Handle("/transactions", handlers.NewTransactionsHandler(sessMgr))
+	http.Handle("/transfers", handlers.NewTransferHandler(sessMgr))
+	http.Handle("/upcoming", handlers.NewUpcomingHandler(sessMgr))
 	http.Handle("/upload", handlers.NewUploadHandler(sessMgr))
 
 	http.Handle("/", http.FileServer(http.Dir("html")))
-	fmt.Println(http.ListenAndServe(fmt.Sprintf(":%d", *port), nil))
+	srv := &http.Server{Addr: fmt.Sprintf(":%d", *port)}
+	errc := make(chan error, 1)
+	go func() {
+		if *tlsCert != "" {
+			errc <- srv.ListenAndServeTLS(*tlsCert, *tlsKey)
+			return
+		}
+		log.Print("warning: serving plain HTTP, so session cookies are not marked Secure; give tls_cert and tls_key to serve HTTPS")
+		errc <- srv.ListenAndServe()
+	}()
+
+	// Let in-flight requests finish and save every user before exiting.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case sig := <-sigs:
+		log.Printf("received %v, shutting down", sig)
+	case err := <-errc:
+		log.Printf("error: ListenAndServe: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("error: Server.Shutdown: %v", err)
+	}
 
 	// var err error
 	// defer func() {
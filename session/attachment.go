@@ -0,0 +1,192 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/groggygopher/oyster/register"
+)
+
+const (
+	// MaxAttachmentSize is the largest attachment, in bytes, that a User may store.
+	MaxAttachmentSize = 5 << 20
+	// MaxNotesLength is the longest memo, in bytes, that a transaction may carry.
+	MaxNotesLength = 4096
+
+	attachmentPostfix = "-attachments"
+)
+
+var (
+	// ErrNoTransaction is returned when a User has no transaction with the requested ID.
+	ErrNoTransaction = errors.New("no such transaction")
+	// ErrNoAttachment is returned when a transaction has no attachment with the requested ID.
+	ErrNoAttachment = errors.New("no such attachment")
+
+	attachmentTypes = map[string]bool{
+		"application/pdf": true,
+		"image/gif":       true,
+		"image/jpeg":      true,
+		"image/png":       true,
+		"image/webp":      true,
+	}
+)
+
+// InputError is returned when a request cannot be carried out as it was made, such as when an
+// attachment is too large, as opposed to when the store fails.
+type InputError struct {
+	err error
+}
+
+func (e *InputError) Error() string {
+	return e.err.Error()
+}
+
+// inputError marks err as caused by the request.
+func inputError(err error) error {
+	return &InputError{err: err}
+}
+
+// transaction returns the User's transaction with the given ID. It must be called while holding
+// u.mu.
+func (u *User) transaction(id string) (*register.Transaction, error) {
+	for _, t := range u.transactions {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return nil, ErrNoTransaction
+}
+
+// SetNotes replaces the memo on the transaction with the given ID.
+func (u *User) SetNotes(id, notes string) error {
+	if len(notes) > MaxNotesLength {
+		return inputError(fmt.Errorf("notes must be at most %d bytes", MaxNotesLength))
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	t, err := u.transaction(id)
+	if err != nil {
+		return err
+	}
+	t.Notes = notes
+	u.touch()
+	return nil
+}
+
+// attachmentPrefix is the store key prefix of the named User's attachments.
+func attachmentPrefix(name string) string {
+	return userKey(name) + attachmentPostfix + "/"
+}
+
+func attachmentKey(name, id string) string {
+	return attachmentPrefix(name) + id
+}
+
+func checkAttachment(contentType string, data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", errors.New("attachment is empty")
+	}
+	if len(data) > MaxAttachmentSize {
+		return "", fmt.Errorf("attachment must be at most %d bytes", MaxAttachmentSize)
+	}
+	declared, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("invalid content type %q: %v", contentType, err)
+	}
+	if !attachmentTypes[declared] {
+		return "", fmt.Errorf("unsupported content type: %s", declared)
+	}
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if sniffed != declared {
+		return "", fmt.Errorf("content type %s does not match the attachment data (%s)", declared, sniffed)
+	}
+	return declared, nil
+}
+
+// AddAttachment encrypts and stores data as a new attachment on the given User's transaction.
+func (m *Manager) AddAttachment(usr *User, transID, name, contentType string, data []byte) (*register.Attachment, error) {
+	contentType, err := checkAttachment(contentType, data)
+	if err != nil {
+		return nil, inputError(err)
+	}
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return nil, fmt.Errorf("io.ReadFull(random): %v", err)
+	}
+	now := time.Now()
+	att := &register.Attachment{
+		ID:          fmt.Sprintf("ATT-%s", hex.EncodeToString(id)),
+		Name:        filepath.Base(name),
+		ContentType: contentType,
+		Size:        len(data),
+		Added:       &now,
+	}
+
+	usr.mu.Lock()
+	defer usr.mu.Unlock()
+	t, err := usr.transaction(transID)
+	if err != nil {
+		return nil, err
+	}
+	key := attachmentKey(usr.Name, att.ID)
+	encrypted, err := seal(usr.passkey, data, []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("seal: %v", err)
+	}
+	if _, err := m.store.Put(key, encrypted); err != nil {
+		return nil, fmt.Errorf("store.Put(%s): %v", key, err)
+	}
+	t.Attachments = append(t.Attachments, att)
+	usr.touch()
+	return att, nil
+}
+
+// Attachment returns an attachment of the given User's transaction along with its decrypted data.
+func (m *Manager) Attachment(usr *User, transID, id string) (*register.Attachment, []byte, error) {
+	usr.mu.Lock()
+	defer usr.mu.Unlock()
+	t, err := usr.transaction(transID)
+	if err != nil {
+		return nil, nil, err
+	}
+	att := t.Attachment(id)
+	if att == nil {
+		return nil, nil, ErrNoAttachment
+	}
+	key := attachmentKey(usr.Name, att.ID)
+	encrypted, _, err := m.store.Get(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("store.Get(%s): %v", key, err)
+	}
+	data, err := unseal(usr.passkey, encrypted, []byte(key))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unseal: %v", err)
+	}
+	return att, data, nil
+}
+
+// DeleteAttachment removes an attachment from the given User's transaction and deletes its data.
+func (m *Manager) DeleteAttachment(usr *User, transID, id string) error {
+	usr.mu.Lock()
+	defer usr.mu.Unlock()
+	t, err := usr.transaction(transID)
+	if err != nil {
+		return err
+	}
+	if !t.RemoveAttachment(id) {
+		return ErrNoAttachment
+	}
+	usr.touch()
+	key := attachmentKey(usr.Name, id)
+	if err := m.store.Delete(key); err != nil {
+		return fmt.Errorf("store.Delete(%s): %v", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/groggygopher/oyster/register"
+)
+
+var pngData = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 32)...)
+
+func TestAttachments(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	usr, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	usr.ImportTransactions([]*register.Transaction{{ID: "trans"}})
+
+	tests := []struct {
+		label       string
+		transID     string
+		contentType string
+		data        []byte
+		wantErr     bool
+	}{
+		{
+			label:       "png",
+			transID:     "trans",
+			contentType: "image/png",
+			data:        pngData,
+		},
+		{
+			label:       "unknown transaction",
+			transID:     "bad",
+			contentType: "image/png",
+			data:        pngData,
+			wantErr:     true,
+		},
+		{
+			label:       "mismatched content type",
+			transID:     "trans",
+			contentType: "application/pdf",
+			data:        pngData,
+			wantErr:     true,
+		},
+		{
+			label:       "unsupported content type",
+			transID:     "trans",
+			contentType: "text/plain",
+			data:        []byte("hello"),
+			wantErr:     true,
+		},
+		{
+			label:       "too large",
+			transID:     "trans",
+			contentType: "image/png",
+			data:        append(pngData, make([]byte, MaxAttachmentSize)...),
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			att, err := m.AddAttachment(usr, test.transID, "receipt.png", test.contentType, test.data)
+			if got, want := err != nil, test.wantErr; got != want {
+				t.Fatalf("wantErr: err: %v, got: %t, want: %t", err, got, want)
+			}
+			if test.wantErr {
+				return
+			}
+			_, data, err := m.Attachment(usr, test.transID, att.ID)
+			if err != nil {
+				t.Fatalf("Attachment: %v", err)
+			}
+			if got, want := data, test.data; !bytes.Equal(got, want) {
+				t.Errorf("data: got: %v, want: %v", got, want)
+			}
+			if err := m.DeleteAttachment(usr, test.transID, att.ID); err != nil {
+				t.Errorf("DeleteAttachment: %v", err)
+			}
+			if _, _, err := m.Attachment(usr, test.transID, att.ID); err != ErrNoAttachment {
+				t.Errorf("Attachment after delete: got: %v, want: %v", err, ErrNoAttachment)
+			}
+		})
+	}
+}
+
+func TestAttachmentSwap(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	usr, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	usr.ImportTransactions([]*register.Transaction{{ID: "trans"}})
+	a, err := m.AddAttachment(usr, "trans", "a.png", "image/png", pngData)
+	if err != nil {
+		t.Fatalf("AddAttachment: %v", err)
+	}
+	b, err := m.AddAttachment(usr, "trans", "b.png", "image/png", append(pngData, 1))
+	if err != nil {
+		t.Fatalf("AddAttachment: %v", err)
+	}
+	// Someone with access to the store puts one attachment's blob in place of the other.
+	blob, _, err := m.store.Get(attachmentKey("test", b.ID))
+	if err != nil {
+		t.Fatalf("store.Get: %v", err)
+	}
+	if _, err := m.store.Put(attachmentKey("test", a.ID), blob); err != nil {
+		t.Fatalf("store.Put: %v", err)
+	}
+	if _, _, err := m.Attachment(usr, "trans", a.ID); err == nil {
+		t.Error("Attachment of a swapped blob: expected non-nil error")
+	}
+}
+
+func TestSetNotes(t *testing.T) {
+	trans := &register.Transaction{ID: "trans"}
+	usr := &User{transactions: []*register.Transaction{trans}}
+	if err := usr.SetNotes("trans", "warranty until 2020"); err != nil {
+		t.Fatalf("SetNotes: %v", err)
+	}
+	if got, want := trans.Notes, "warranty until 2020"; got != want {
+		t.Errorf("notes: got: %s, want: %s", got, want)
+	}
+	if err := usr.SetNotes("bad", "notes"); err != ErrNoTransaction {
+		t.Errorf("unknown transaction: got: %v, want: %v", err, ErrNoTransaction)
+	}
+	if err := usr.SetNotes("trans", strings.Repeat("a", MaxNotesLength+1)); err == nil {
+		t.Error("expected non-nil error for notes that are too long")
+	}
+}
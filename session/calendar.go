@@ -0,0 +1,193 @@
+package session
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/groggygopher/oyster/recurring"
+)
+
+const calendarPrefix = "calendar-"
+
+// ErrNoCalendar is returned when no calendar feed exists for a token.
+var ErrNoCalendar = errors.New("no such calendar")
+
+func randomID(prefix string) (string, error) {
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return "", fmt.Errorf("io.ReadFull(random): %v", err)
+	}
+	return prefix + hex.EncodeToString(id), nil
+}
+
+// Schedules returns this User's scheduled transactions.
+func (u *User) Schedules() []*recurring.Schedule {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.schedules
+}
+
+// AddSchedule validates and adds a scheduled transaction to this User, assigning it a new ID.
+func (u *User) AddSchedule(s *recurring.Schedule) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	id, err := randomID("SCHED-")
+	if err != nil {
+		return err
+	}
+	s.ID = id
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if s.Category != "" && !u.categories.Has(s.Category) {
+		return fmt.Errorf("unknown category: %s", s.Category)
+	}
+	u.schedules = append(u.schedules, s)
+	u.touch()
+	return nil
+}
+
+// DeleteSchedule removes the scheduled transaction with the given ID, returning true if it existed.
+func (u *User) DeleteSchedule(id string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for i, s := range u.schedules {
+		if s.ID == id {
+			u.schedules = append(u.schedules[:i], u.schedules[i+1:]...)
+			u.touch()
+			return true
+		}
+	}
+	return false
+}
+
+// Upcoming returns this User's predicted and scheduled transactions from now through the given
+// number of days.
+func (u *User) Upcoming(now time.Time, days int) []*recurring.Occurrence {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return recurring.Upcoming(recurring.Analyze(u.transactions, now), u.schedules, now, days)
+}
+
+// calendarFeed is what a calendar token can unlock without the User's password. It holds only what
+// is needed to predict upcoming transactions.
+type calendarFeed struct {
+	Name      string
+	Series    []*recurring.Series
+	Schedules []*recurring.Schedule
+}
+
+// calendarKeys derives the store key and the encryption key of a calendar feed from its token.
+func calendarKeys(token string) (string, []byte) {
+	id := sha256.Sum256([]byte("oyster-calendar-id:" + token))
+	key := sha256.Sum256([]byte("oyster-calendar-key:" + token))
+	return calendarPrefix + hex.EncodeToString(id[:]), key[:]
+}
+
+// writeCalendar refreshes the encrypted calendar feed of a User with a calendar token.
+func (m *Manager) writeCalendar(usr *User) error {
+	usr.mu.Lock()
+	token := usr.calendarToken
+	if token == "" {
+		usr.mu.Unlock()
+		return nil
+	}
+	feed := &calendarFeed{
+		Name:      usr.Name,
+		Series:    recurring.Analyze(usr.transactions, time.Now()),
+		Schedules: usr.schedules,
+	}
+	usr.mu.Unlock()
+
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(feed); err != nil {
+		return fmt.Errorf("json.Encode: %v", err)
+	}
+	storeKey, key := calendarKeys(token)
+	encrypted, err := seal(key, buf.Bytes(), nil)
+	if err != nil {
+		return fmt.Errorf("seal: %v", err)
+	}
+	if _, err := m.store.Put(storeKey, encrypted); err != nil {
+		return fmt.Errorf("store.Put(%s): %v", storeKey, err)
+	}
+	return nil
+}
+
+func (m *Manager) removeCalendar(token string) error {
+	if token == "" {
+		return nil
+	}
+	storeKey, _ := calendarKeys(token)
+	if err := m.store.Delete(storeKey); err != nil {
+		return fmt.Errorf("store.Delete(%s): %v", storeKey, err)
+	}
+	return nil
+}
+
+// RotateCalendarToken issues a new secret calendar token for the given User, revoking any previous
+// one, and writes the User's calendar feed.
+func (m *Manager) RotateCalendarToken(usr *User) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return "", fmt.Errorf("io.ReadFull(random): %v", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	usr.mu.Lock()
+	old := usr.calendarToken
+	usr.calendarToken = token
+	usr.touch()
+	usr.mu.Unlock()
+
+	if err := m.removeCalendar(old); err != nil {
+		return "", err
+	}
+	if err := m.writeCalendar(usr); err != nil {
+		return "", fmt.Errorf("writeCalendar: %v", err)
+	}
+	return token, nil
+}
+
+// DisableCalendar revokes the given User's calendar token and deletes the feed.
+func (m *Manager) DisableCalendar(usr *User) error {
+	usr.mu.Lock()
+	old := usr.calendarToken
+	usr.calendarToken = ""
+	usr.touch()
+	usr.mu.Unlock()
+	return m.removeCalendar(old)
+}
+
+// Calendar returns the name of the User with the given calendar token along with their upcoming
+// transactions from now through the given number of days. No session is needed.
+func (m *Manager) Calendar(token string, now time.Time, days int) (string, []*recurring.Occurrence, error) {
+	if token == "" {
+		return "", nil, ErrNoCalendar
+	}
+	storeKey, key := calendarKeys(token)
+	encrypted, _, err := m.store.Get(storeKey)
+	if err == ErrNoObject {
+		return "", nil, ErrNoCalendar
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("store.Get(%s): %v", storeKey, err)
+	}
+	plain, err := unseal(key, encrypted, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("unseal: %v", err)
+	}
+	feed := &calendarFeed{}
+	if err := json.Unmarshal(plain, feed); err != nil {
+		return "", nil, fmt.Errorf("json.Unmarshal: %v", err)
+	}
+	return feed.Name, recurring.Upcoming(feed.Series, feed.Schedules, now, days), nil
+}
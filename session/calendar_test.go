@@ -0,0 +1,52 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/groggygopher/oyster/recurring"
+)
+
+func TestCalendar(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	usr, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	now := time.Now()
+	if err := usr.AddSchedule(&recurring.Schedule{Description: "rent", Cadence: recurring.Monthly, Start: &now}); err != nil {
+		t.Fatalf("AddSchedule: %v", err)
+	}
+
+	token, err := m.RotateCalendarToken(usr)
+	if err != nil {
+		t.Fatalf("RotateCalendarToken: %v", err)
+	}
+	name, occs, err := m.Calendar(token, now, 40)
+	if err != nil {
+		t.Fatalf("Calendar: %v", err)
+	}
+	if got, want := name, "test"; got != want {
+		t.Errorf("name: got: %s, want: %s", got, want)
+	}
+	if got, want := len(occs), 2; got != want {
+		t.Errorf("occurrences: got: %d, want: %d", got, want)
+	}
+
+	newToken, err := m.RotateCalendarToken(usr)
+	if err != nil {
+		t.Fatalf("RotateCalendarToken: %v", err)
+	}
+	if _, _, err := m.Calendar(token, now, 40); err != ErrNoCalendar {
+		t.Errorf("rotated token: got: %v, want: %v", err, ErrNoCalendar)
+	}
+	if err := m.DisableCalendar(usr); err != nil {
+		t.Fatalf("DisableCalendar: %v", err)
+	}
+	if _, _, err := m.Calendar(newToken, now, 40); err != ErrNoCalendar {
+		t.Errorf("disabled token: got: %v, want: %v", err, ErrNoCalendar)
+	}
+}
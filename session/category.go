@@ -0,0 +1,144 @@
+package session
+
+import (
+	"fmt"
+
+	"github.com/groggygopher/oyster/register"
+)
+
+func seedCategories(trans []*register.Transaction, rules []string) *register.CategoryTree {
+	ct := register.NewCategoryTree(rules...)
+	for _, t := range trans {
+		for _, c := range t.Category {
+			ct.Add(c.Name)
+		}
+	}
+	return ct
+}
+
+// HasCategory returns true if the given name is in this User's category tree.
+func (u *User) HasCategory(name string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.categories.Has(name)
+}
+
+// AddCategory adds the given category, and any missing parents, to this User's category tree.
+func (u *User) AddCategory(name string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := u.categories.Add(name); err != nil {
+		return err
+	}
+	u.touch()
+	return nil
+}
+
+// RemoveCategory removes a category from this User's tree so long as no sub-category, transaction,
+// rule, schedule or budget still uses it.
+func (u *User) RemoveCategory(name string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, t := range u.transactions {
+		for _, c := range t.Category {
+			if c.Name == name {
+				return fmt.Errorf("category %s is used by transaction %s", name, t.ID)
+			}
+		}
+	}
+	for _, r := range u.manager.Rules() {
+		if r.Category == name {
+			return fmt.Errorf("category %s is used by rule %s", name, r.Name)
+		}
+	}
+	for _, s := range u.schedules {
+		if s.Category == name {
+			return fmt.Errorf("category %s is used by schedule %s", name, s.Description)
+		}
+	}
+	for _, b := range u.budgets {
+		if b.Name() == name {
+			return fmt.Errorf("category %s has a budget", name)
+		}
+	}
+	if err := u.categories.Remove(name); err != nil {
+		return err
+	}
+	u.touch()
+	return nil
+}
+
+// RenameCategory renames a category and all of its sub-categories, rewriting every transaction,
+// rule, schedule and budget that uses them.
+func (u *User) RenameCategory(old, new string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := u.categories.Rename(old, new); err != nil {
+		return err
+	}
+	u.moveCategory(old, new)
+	u.touch()
+	return nil
+}
+
+// MergeCategory folds the src category into the existing dst category, rewriting every
+// transaction, rule, schedule and budget that uses src or one of its sub-categories. Budgets that
+// land on a category which already has one are added to it.
+func (u *User) MergeCategory(src, dst string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := u.categories.Merge(src, dst); err != nil {
+		return err
+	}
+	u.moveCategory(src, dst)
+	u.touch()
+	return nil
+}
+
+// moveCategory must be called while holding u.mu.
+func (u *User) moveCategory(old, new string) {
+	for _, t := range u.transactions {
+		var cats []*register.Category
+		byName := make(map[string]*register.Category)
+		for _, c := range t.Category {
+			c.Name, _ = register.MoveCategory(c.Name, old, new)
+			// A merge can leave a transaction with two entries against the same category.
+			if prev, ok := byName[c.Name]; ok {
+				prev.Amount += c.Amount
+				continue
+			}
+			byName[c.Name] = c
+			cats = append(cats, c)
+		}
+		t.Category = cats
+	}
+	u.manager.MoveCategory(old, new)
+	for _, s := range u.schedules {
+		s.Category, _ = register.MoveCategory(s.Category, old, new)
+	}
+
+	byName := make(map[string]*register.Budget)
+	for _, b := range u.budgets {
+		byName[b.Name()] = b
+	}
+	var budgets []*register.Budget
+	for _, b := range u.budgets {
+		if moved, ok := register.MoveCategory(b.Name(), old, new); ok {
+			if prev, ok := byName[moved]; ok && prev != b {
+				prev.Merge(b)
+				continue
+			}
+			b.Rename(moved)
+		}
+		budgets = append(budgets, b)
+	}
+	u.budgets = budgets
+}
+
+// CategoryTotals returns the amount spent against every category in this User's tree, with the
+// spending of sub-categories rolled up into their parents.
+func (u *User) CategoryTotals() []*register.CategoryTotal {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.categories.RollUp(u.transactions)
+}
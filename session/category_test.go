@@ -0,0 +1,130 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/groggygopher/oyster/recurring"
+	"github.com/groggygopher/oyster/register"
+	"github.com/groggygopher/oyster/rule"
+)
+
+func TestMergeCategory(t *testing.T) {
+	r := &rule.Rule{Name: "costco", Category: "Food:Grocery"}
+	trans := &register.Transaction{
+		ID: "trans",
+		Category: []*register.Category{
+			{Name: "Food:Grocery", Amount: -10},
+			{Name: "Food:Groceries", Amount: -5},
+		},
+	}
+	src := register.NewBudget("Food:Grocery")
+	src.SetAmount(time.March, 2018, 100)
+	src.SetAmount(time.April, 2018, 50)
+	dst := register.NewBudget("Food:Groceries")
+	dst.SetAmount(time.March, 2018, 200)
+	usr := &User{
+		transactions: []*register.Transaction{trans},
+		manager:      rule.NewManager([]*rule.Rule{r}),
+		categories:   register.NewCategoryTree("Food:Grocery", "Food:Groceries"),
+		budgets:      []*register.Budget{src, dst},
+	}
+
+	if err := usr.MergeCategory("Food:Grocery", "Food:Groceries"); err != nil {
+		t.Fatalf("MergeCategory: %v", err)
+	}
+	if got, want := trans.Category, []*register.Category{{Name: "Food:Groceries", Amount: -15}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("transaction categories: got: %v, want: %v", got, want)
+	}
+	if got, want := r.Category, "Food:Groceries"; got != want {
+		t.Errorf("rule category: got: %s, want: %s", got, want)
+	}
+	if usr.HasCategory("Food:Grocery") {
+		t.Error("merged category should be removed")
+	}
+	if got, want := usr.Budgets(), []*register.Budget{dst}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("budgets: got: %v, want: %v", got, want)
+	}
+	for _, test := range []struct {
+		month time.Month
+		want  float64
+	}{
+		{time.March, 300},
+		{time.April, 50},
+	} {
+		if got, _ := dst.Amount(test.month, 2018); got != test.want {
+			t.Errorf("%s budget: got: %f, want: %f", test.month, got, test.want)
+		}
+	}
+}
+
+func TestRenameCategory(t *testing.T) {
+	r := &rule.Rule{Name: "costco", Category: "Food:Groceries"}
+	trans := &register.Transaction{
+		ID:       "trans",
+		Category: []*register.Category{{Name: "Food:Groceries", Amount: -10}},
+	}
+	b := register.NewBudget("Food:Groceries")
+	b.SetAmount(time.March, 2018, 100)
+	sched := &recurring.Schedule{Description: "farm box", Category: "Food:Groceries"}
+	usr := &User{
+		transactions: []*register.Transaction{trans},
+		manager:      rule.NewManager([]*rule.Rule{r}),
+		categories:   register.NewCategoryTree("Food:Groceries"),
+		budgets:      []*register.Budget{b},
+		schedules:    []*recurring.Schedule{sched},
+	}
+
+	if err := usr.RenameCategory("Food", "Eating"); err != nil {
+		t.Fatalf("RenameCategory: %v", err)
+	}
+	if got, want := trans.Category[0].Name, "Eating:Groceries"; got != want {
+		t.Errorf("transaction category: got: %s, want: %s", got, want)
+	}
+	if got, want := r.Category, "Eating:Groceries"; got != want {
+		t.Errorf("rule category: got: %s, want: %s", got, want)
+	}
+	if got, want := b.Name(), "Eating:Groceries"; got != want {
+		t.Errorf("budget name: got: %s, want: %s", got, want)
+	}
+	if got, want := sched.Category, "Eating:Groceries"; got != want {
+		t.Errorf("schedule category: got: %s, want: %s", got, want)
+	}
+	if err := usr.RemoveCategory("Eating:Groceries"); err == nil {
+		t.Error("expected non-nil error removing a category in use")
+	}
+}
+
+func TestRemoveBudgetedCategory(t *testing.T) {
+	b := register.NewBudget("Food:Groceries")
+	b.SetAmount(time.March, 2018, 100)
+	usr := &User{
+		manager:    rule.NewManager(nil),
+		categories: register.NewCategoryTree("Food:Groceries"),
+		budgets:    []*register.Budget{b},
+	}
+	if err := usr.RemoveCategory("Food:Groceries"); err == nil {
+		t.Error("expected non-nil error removing a budgeted category")
+	}
+	if !usr.HasCategory("Food:Groceries") {
+		t.Error("budgeted category should be kept")
+	}
+}
+
+func TestDeserializeSeedsCategories(t *testing.T) {
+	// Users saved before categories were managed have no Categories and no schema version.
+	bs := gzipBytes(t, []byte(`{
+		"Name": "test",
+		"Transactions": [{"categories": [{"Name": "Food:Groceries"}]}],
+		"Rules": [{"name": "rent", "category": "Bills:Rent"}]
+	}`))
+	deser, err := DeserializeUser(bs)
+	if err != nil {
+		t.Fatalf("DeserializeUser: %v", err)
+	}
+	want := []string{"Bills", "Bills:Rent", "Food", "Food:Groceries"}
+	if got := deser.categories.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/groggygopher/oyster/register"
+)
+
+// Duplicates returns the suspected duplicates among this User's transactions, at most the given
+// number of days apart, that have not been dismissed.
+func (u *User) Duplicates(days int) []*register.DuplicatePair {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	dismissed := make(map[string]bool)
+	for _, k := range u.dismissed {
+		dismissed[k] = true
+	}
+	var pairs []*register.DuplicatePair
+	for _, p := range register.FindDuplicates(u.transactions, days) {
+		if !dismissed[p.Key()] {
+			pairs = append(pairs, p)
+		}
+	}
+	return pairs
+}
+
+// DismissDuplicate records that the two transactions with the given IDs are distinct, so that
+// they are no longer reported as duplicates of each other.
+func (u *User) DismissDuplicate(a, b string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if a == b {
+		return inputError(fmt.Errorf("transaction %s cannot be a duplicate of itself", a))
+	}
+	for _, id := range []string{a, b} {
+		if _, err := u.transaction(id); err != nil {
+			return err
+		}
+	}
+	key := register.DuplicateKey(a, b)
+	i := sort.SearchStrings(u.dismissed, key)
+	if i < len(u.dismissed) && u.dismissed[i] == key {
+		return nil
+	}
+	u.dismissed = append(u.dismissed, "")
+	copy(u.dismissed[i+1:], u.dismissed[i:])
+	u.dismissed[i] = key
+	u.touch()
+	return nil
+}
+
+// MergeDuplicate folds the transaction with ID dropID into the one with ID keepID and deletes it.
+// Tags, notes and attachments are carried over, and a transfer link on the dropped copy moves to
+// the kept one. The dropped ID is remembered so that importing it again does not restore it.
+func (u *User) MergeDuplicate(keepID, dropID string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if keepID == dropID {
+		return inputError(fmt.Errorf("transaction %s cannot be merged into itself", keepID))
+	}
+	keep, err := u.transaction(keepID)
+	if err != nil {
+		return err
+	}
+	drop, err := u.transaction(dropID)
+	if err != nil {
+		return err
+	}
+
+	if drop.IsTransfer() {
+		if other, err := u.transaction(drop.TransferID); err == nil {
+			if !keep.IsTransfer() && other != keep {
+				keep.TransferID = other.ID
+				other.TransferID = keep.ID
+			} else {
+				other.TransferID = ""
+			}
+		}
+		drop.TransferID = ""
+	}
+	register.MergeDuplicate(keep, drop)
+
+	for i, t := range u.transactions {
+		if t == drop {
+			u.transactions = append(u.transactions[:i], u.transactions[i+1:]...)
+			break
+		}
+	}
+	u.merged = append(u.merged, dropID)
+	u.touch()
+	return nil
+}
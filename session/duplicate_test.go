@@ -0,0 +1,50 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/groggygopher/oyster/register"
+)
+
+func TestMergeDismissDuplicate(t *testing.T) {
+	day := time.Date(2018, time.May, 1, 0, 0, 0, 0, time.UTC)
+	newTrans := func() []*register.Transaction {
+		return []*register.Transaction{
+			{ID: "a", Description: "COFFEE", Amount: -5, Date: &day, Tags: []string{"work"}},
+			{ID: "b", Description: "COFFEE", Amount: -5, Date: &day, TransferID: "c"},
+			{ID: "c", Description: "TRANSFER", Amount: 5, Date: &day, TransferID: "b"},
+		}
+	}
+	trans := newTrans()
+	usr := &User{transactions: trans}
+
+	if got, want := len(usr.Duplicates(register.DefaultDuplicateDays)), 1; got != want {
+		t.Fatalf("duplicates: got: %d, want: %d", got, want)
+	}
+	if err := usr.DismissDuplicate("a", "missing"); err != ErrNoTransaction {
+		t.Errorf("unknown transaction: got: %v, want: %v", err, ErrNoTransaction)
+	}
+	if err := usr.DismissDuplicate("b", "a"); err != nil {
+		t.Fatalf("DismissDuplicate: %v", err)
+	}
+	if got, want := len(usr.Duplicates(register.DefaultDuplicateDays)), 0; got != want {
+		t.Errorf("duplicates after dismiss: got: %d, want: %d", got, want)
+	}
+
+	if err := usr.MergeDuplicate("a", "b"); err != nil {
+		t.Fatalf("MergeDuplicate: %v", err)
+	}
+	if got, want := len(usr.Transactions()), 2; got != want {
+		t.Errorf("transactions after merge: got: %d, want: %d", got, want)
+	}
+	if got, want := trans[0].TransferID, "c"; got != want {
+		t.Errorf("kept transfer ID: got: %s, want: %s", got, want)
+	}
+	if got, want := trans[2].TransferID, "a"; got != want {
+		t.Errorf("other half transfer ID: got: %s, want: %s", got, want)
+	}
+	if got, want := usr.ImportTransactions(newTrans()), 0; got != want {
+		t.Errorf("re-imported transactions: got: %d, want: %d", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+package session
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/groggygopher/oyster/forecast"
+	"github.com/groggygopher/oyster/recurring"
+	"github.com/groggygopher/oyster/register"
+)
+
+// Accounts returns this User's accounts.
+func (u *User) Accounts() []*register.Account {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.accounts
+}
+
+// SetAccount adds the given account to this User, replacing any account with the same name.
+func (u *User) SetAccount(a *register.Account) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.touch()
+	for i, old := range u.accounts {
+		if old.Name == a.Name {
+			u.accounts[i] = a
+			return nil
+		}
+	}
+	u.accounts = append(u.accounts, a)
+	return nil
+}
+
+// DeleteAccount removes the account with the given name, returning true if it existed.
+func (u *User) DeleteAccount(name string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for i, a := range u.accounts {
+		if a.Name == name {
+			u.accounts = append(u.accounts[:i], u.accounts[i+1:]...)
+			u.touch()
+			return true
+		}
+	}
+	return false
+}
+
+// Budgets returns this User's budgets.
+func (u *User) Budgets() []*register.Budget {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.budgets
+}
+
+// SetBudget sets the amount budgeted for a category in the given month, creating the category's
+// budget if needed.
+func (u *User) SetBudget(category string, month time.Month, year int, amount float64) error {
+	if month < time.January || month > time.December {
+		return fmt.Errorf("invalid month: %d", month)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.categories.Has(category) {
+		return fmt.Errorf("unknown category: %s", category)
+	}
+	u.touch()
+	for _, b := range u.budgets {
+		if b.Name() == category {
+			b.SetAmount(month, year, amount)
+			return nil
+		}
+	}
+	b := register.NewBudget(category)
+	b.SetAmount(month, year, amount)
+	u.budgets = append(u.budgets, b)
+	return nil
+}
+
+// Forecast projects the daily balance of every one of this User's accounts from now through the
+// given number of days, using scheduled and recurring transactions and budgeted amounts.
+func (u *User) Forecast(now time.Time, days int, defaultAccount string) []*forecast.Projection {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	upcoming := recurring.Upcoming(recurring.Analyze(u.transactions, now), u.schedules, now, days)
+	return forecast.Project(u.accounts, u.transactions, upcoming, u.budgets, &forecast.Options{
+		From:           now,
+		Days:           days,
+		DefaultAccount: defaultAccount,
+	})
+}
@@ -0,0 +1,210 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/groggygopher/oyster/register"
+)
+
+// ErrNoImport is returned when a User has no import batch with the requested ID.
+var ErrNoImport = errors.New("no such import")
+
+// importChunkSize is how many rows an import reads before checking them against the User's
+// index, which bounds how long the User is locked at a time and how many rows are held in memory.
+const importChunkSize = 1000
+
+// previewSampleSize is how many of the transactions that an import would add are returned by
+// PreviewImport.
+const previewSampleSize = 50
+
+// ImportBatch records a single upload of transactions so that it can be reviewed or rolled back.
+// Rows is the number of transactions in the file and IDs are the ones it added.
+type ImportBatch struct {
+	ID       string    `json:"id"`
+	Filename string    `json:"filename"`
+	Account  string    `json:"account"`
+	Time     time.Time `json:"time"`
+	Rows     int       `json:"rows"`
+	Imported int       `json:"imported"`
+	IDs      []string  `json:"ids"`
+}
+
+// ImportPreview describes what importing a source would do: the number of rows read, how many of
+// them are new to the User, and the first of the new transactions.
+type ImportPreview struct {
+	Rows   int
+	New    int
+	Sample []*register.Transaction
+}
+
+// readChunks reads the given source to the end in chunks of importChunkSize rows, assigning each
+// transaction to the given account if it is not empty. The transactions of each chunk that this
+// User does not already have are passed to commit while holding u.mu, and the number of rows read
+// is returned. Only the current chunk is checked for duplicates besides the User's index, so
+// commit must add the new transactions to the index for later chunks to skip them.
+func (u *User) readChunks(src register.Source, account string, commit func([]*register.Transaction)) (int, error) {
+	var chunk []*register.Transaction
+	var rows int
+	flush := func() {
+		u.mu.Lock()
+		commit(u.newTransactions(chunk, make(map[string]bool)))
+		u.mu.Unlock()
+		chunk = chunk[:0]
+	}
+	for {
+		t, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rows, err
+		}
+		rows++
+		if account != "" {
+			t.Account = account
+		}
+		if chunk = append(chunk, t); len(chunk) == importChunkSize {
+			flush()
+		}
+	}
+	flush()
+	return rows, nil
+}
+
+// PreviewImport reads the given source without importing it, and returns how many of its
+// transactions are new along with a sample of them. A transaction repeated in the source more than
+// importChunkSize rows apart is counted as new twice.
+func (u *User) PreviewImport(src register.Source, account string) (*ImportPreview, error) {
+	preview := &ImportPreview{}
+	rows, err := u.readChunks(src, account, func(fresh []*register.Transaction) {
+		preview.New += len(fresh)
+		for _, t := range fresh {
+			if len(preview.Sample) == previewSampleSize {
+				break
+			}
+			preview.Sample = append(preview.Sample, t)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	preview.Rows = rows
+	return preview, nil
+}
+
+// Import streams the transactions of the given source, read from the named file, into this User
+// and records them as a new ImportBatch. Each chunk of new transactions is added as soon as it is
+// read, and the batch is rolled back if the source fails, so nothing is imported then.
+func (u *User) Import(src register.Source, filename, account string, now time.Time) (*ImportBatch, error) {
+	id, err := randomID("IMPORT-")
+	if err != nil {
+		return nil, err
+	}
+	batch := &ImportBatch{
+		ID:       id,
+		Filename: filename,
+		Account:  account,
+		Time:     now,
+	}
+	u.mu.Lock()
+	u.imports = append(u.imports, batch)
+	u.touch()
+	u.mu.Unlock()
+
+	rows, err := u.readChunks(src, account, func(fresh []*register.Transaction) {
+		u.addTransactions(fresh)
+		for _, t := range fresh {
+			batch.IDs = append(batch.IDs, t.ID)
+		}
+		batch.Imported += len(fresh)
+		u.touch()
+	})
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err != nil {
+		// The batch may already be gone if it was rolled back while the source was read.
+		if _, rerr := u.rollbackImport(id); rerr != nil && rerr != ErrNoImport {
+			return nil, fmt.Errorf("rollbackImport(%s): %v after %v", id, rerr, err)
+		}
+		return nil, err
+	}
+	batch.Rows = rows
+	u.touch()
+	return batch, nil
+}
+
+// ImportBatches returns this User's import batches, oldest first.
+func (u *User) ImportBatches() []*ImportBatch {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.imports
+}
+
+// RollbackImport removes every transaction that the given User's import batch with the given ID
+// added, along with their attachments, and forgets the batch. Transfers linked to a removed
+// transaction are unlinked. The number of removed transactions is returned.
+func (m *Manager) RollbackImport(usr *User, id string) (int, error) {
+	usr.mu.Lock()
+	defer usr.mu.Unlock()
+	removed, err := usr.rollbackImport(id)
+	if err != nil {
+		return 0, err
+	}
+	for _, t := range removed {
+		for _, a := range t.Attachments {
+			key := attachmentKey(usr.Name, a.ID)
+			if err := m.store.Delete(key); err != nil {
+				return len(removed), fmt.Errorf("store.Delete(%s): %v", key, err)
+			}
+		}
+	}
+	return len(removed), nil
+}
+
+// rollbackImport removes every transaction that the import batch with the given ID added, unlinks
+// their transfers and forgets the batch, returning the removed transactions so that their
+// attachments can be deleted. It must be called while holding u.mu.
+func (u *User) rollbackImport(id string) ([]*register.Transaction, error) {
+	var batch *ImportBatch
+	var batches []*ImportBatch
+	for _, b := range u.imports {
+		if b.ID == id {
+			batch = b
+		} else {
+			batches = append(batches, b)
+		}
+	}
+	if batch == nil {
+		return nil, ErrNoImport
+	}
+	ids := make(map[string]bool)
+	for _, id := range batch.IDs {
+		ids[id] = true
+	}
+
+	var kept, removed []*register.Transaction
+	for _, t := range u.transactions {
+		if ids[t.ID] {
+			removed = append(removed, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+	for _, t := range kept {
+		if ids[t.TransferID] {
+			t.TransferID = ""
+		}
+	}
+	u.transactions = kept
+	u.imports = batches
+	u.touch()
+	index := u.index()
+	for _, t := range removed {
+		delete(index, t.ID)
+	}
+	return removed, nil
+}
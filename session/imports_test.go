@@ -0,0 +1,158 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/groggygopher/oyster/register"
+)
+
+func TestImportRollback(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	usr, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	now := time.Now()
+	usr.ImportTransactions([]*register.Transaction{
+		{ID: "old", Amount: 10, Date: &now},
+	})
+	trans := []*register.Transaction{
+		{ID: "old", Amount: 10, Date: &now},
+		{ID: "new", Amount: -10, Date: &now},
+		{ID: "new", Amount: -10, Date: &now},
+	}
+
+	preview, err := usr.PreviewImport(register.SliceSource(trans), "")
+	if err != nil {
+		t.Fatalf("PreviewImport: %v", err)
+	}
+	if got, want := preview.Rows, 3; got != want {
+		t.Errorf("preview rows: got: %d, want: %d", got, want)
+	}
+	if got, want := preview.New, 1; got != want {
+		t.Errorf("preview new: got: %d, want: %d", got, want)
+	}
+	if got, want := len(preview.Sample), 1; got != want {
+		t.Fatalf("preview sample: got: %d, want: %d", got, want)
+	}
+	if got, want := len(usr.Transactions()), 1; got != want {
+		t.Errorf("transactions after preview: got: %d, want: %d", got, want)
+	}
+
+	batch, err := usr.Import(register.SliceSource(trans), "march.csv", "checking", now)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if got, want := batch.Rows, 3; got != want {
+		t.Errorf("rows: got: %d, want: %d", got, want)
+	}
+	if got, want := batch.Imported, 1; got != want {
+		t.Errorf("imported: got: %d, want: %d", got, want)
+	}
+	if got, want := usr.Transactions()[1].Account, "checking"; got != want {
+		t.Errorf("account: got: %s, want: %s", got, want)
+	}
+	if err := usr.LinkTransfer("new", "old"); err != nil {
+		t.Fatalf("LinkTransfer: %v", err)
+	}
+	att, err := m.AddAttachment(usr, "new", "receipt.png", "image/png", pngData)
+	if err != nil {
+		t.Fatalf("AddAttachment: %v", err)
+	}
+
+	if _, err := m.RollbackImport(usr, "bad"); err != ErrNoImport {
+		t.Errorf("unknown import: got: %v, want: %v", err, ErrNoImport)
+	}
+	removed, err := m.RollbackImport(usr, batch.ID)
+	if err != nil {
+		t.Fatalf("RollbackImport: %v", err)
+	}
+	if got, want := removed, 1; got != want {
+		t.Errorf("removed: got: %d, want: %d", got, want)
+	}
+	if got, want := len(usr.Transactions()), 1; got != want {
+		t.Errorf("transactions after rollback: got: %d, want: %d", got, want)
+	}
+	if usr.Transactions()[0].IsTransfer() {
+		t.Error("remaining transaction should be unlinked")
+	}
+	if got, want := len(usr.ImportBatches()), 0; got != want {
+		t.Errorf("batches after rollback: got: %d, want: %d", got, want)
+	}
+	if _, _, err := m.store.Get(attachmentKey("test", att.ID)); err != ErrNoObject {
+		t.Errorf("attachment should be removed, get: %v", err)
+	}
+}
+
+// failingSource yields its transactions and then fails.
+type failingSource struct {
+	trans []*register.Transaction
+}
+
+func (s *failingSource) Next() (*register.Transaction, error) {
+	if len(s.trans) == 0 {
+		return nil, errors.New("read failed")
+	}
+	t := s.trans[0]
+	s.trans = s.trans[1:]
+	return t, nil
+}
+
+func TestImportChunks(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	usr, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	now := time.Now()
+	var trans []*register.Transaction
+	for i := 0; i < importChunkSize+previewSampleSize; i++ {
+		trans = append(trans, &register.Transaction{ID: fmt.Sprintf("t%d", i), Amount: -1, Date: &now})
+	}
+	// Repeats the first row in the next chunk.
+	trans = append(trans, &register.Transaction{ID: "t0", Amount: -1, Date: &now})
+
+	if _, err := usr.Import(&failingSource{trans: trans}, "broken.csv", "", now); err == nil {
+		t.Fatal("expected non-nil error importing a failing source")
+	}
+	if got, want := len(usr.Transactions()), 0; got != want {
+		t.Errorf("transactions after failed import: got: %d, want: %d", got, want)
+	}
+	if got, want := len(usr.ImportBatches()), 0; got != want {
+		t.Errorf("batches after failed import: got: %d, want: %d", got, want)
+	}
+
+	preview, err := usr.PreviewImport(register.SliceSource(trans), "")
+	if err != nil {
+		t.Fatalf("PreviewImport: %v", err)
+	}
+	if got, want := preview.Rows, len(trans); got != want {
+		t.Errorf("preview rows: got: %d, want: %d", got, want)
+	}
+	if got, want := len(preview.Sample), previewSampleSize; got != want {
+		t.Errorf("preview sample: got: %d, want: %d", got, want)
+	}
+
+	batch, err := usr.Import(register.SliceSource(trans), "march.csv", "", now)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if got, want := batch.Rows, len(trans); got != want {
+		t.Errorf("rows: got: %d, want: %d", got, want)
+	}
+	if got, want := batch.Imported, len(trans)-1; got != want {
+		t.Errorf("imported: got: %d, want: %d", got, want)
+	}
+	if got, want := len(usr.Transactions()), len(trans)-1; got != want {
+		t.Errorf("transactions: got: %d, want: %d", got, want)
+	}
+}
@@ -9,30 +9,47 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/groggygopher/oyster/payee"
+	"github.com/groggygopher/oyster/register"
 	"github.com/groggygopher/oyster/rule"
 )
 
 const (
 	postfix   = "-Oyster"
-	cost      = 13
 	nonceLen  = 12
 	aesKeyLen = 32
 )
 
-func decodeUser(saveFile string, passkey []byte) (*User, error) {
-	file, err := ioutil.ReadFile(saveFile)
+// seal encrypts plain with AES-GCM under the given passkey, returning the random nonce followed by
+// the ciphertext. The additional data aad is authenticated but not encrypted, and must be passed
+// to unseal unchanged.
+func seal(passkey, plain, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(passkey)
+	if err != nil {
+		return nil, fmt.Errorf("aes.NewCipher: %v", err)
+	}
+	aesgcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return nil, fmt.Errorf("ioutil.ReadFile(%s): %v", saveFile, err)
+		return nil, fmt.Errorf("cipher.NewGCM: %v", err)
+	}
+	nonce := make([]byte, nonceLen)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("io.ReadFull(random): %v", err)
+	}
+	return aesgcm.Seal(nonce, nonce, plain, aad), nil
+}
+
+// unseal decrypts data produced by seal with the given passkey and additional data.
+func unseal(passkey, data, aad []byte) ([]byte, error) {
+	if len(data) < nonceLen {
+		return nil, errors.New("encrypted data is too short")
 	}
-	nonce := file[:nonceLen]
-	encrypted := file[nonceLen:]
+	nonce := data[:nonceLen]
+	encrypted := data[nonceLen:]
 
 	block, err := aes.NewCipher(passkey)
 	if err != nil {
@@ -42,62 +59,94 @@ func decodeUser(saveFile string, passkey []byte) (*User, error) {
 	if err != nil {
 		return nil, fmt.Errorf("cipher.NewGCM: %v", err)
 	}
-	plain, err := aesgcm.Open(nil, nonce, encrypted, nil)
+	plain, err := aesgcm.Open(nil, nonce, encrypted, aad)
 	if err != nil {
 		return nil, fmt.Errorf("aesgcm.Open: %v", err)
 	}
-
-	usr, err := DeserializeUser(plain)
-	if err != nil {
-		return nil, fmt.Errorf("DeserializeUser: %v", err)
-	}
-	usr.passkey = passkey
-	return usr, nil
+	return plain, nil
 }
 
-func encodeUser(usr *User, saveFile string) error {
-	passkey := usr.passkey
-	// Don't serialize the passkey, but add it back before returning.
-	usr.passkey = nil
-	defer func() {
-		usr.passkey = passkey
-	}()
-
-	serUsr, err := usr.Serialize()
+// decodeUser decrypts the User in the given save file with the key slot of the given kind that
+// secret opens, and returns the vault version the file was written with. Legacy files can only be
+// opened with the password, which derives the unsalted key their User is sealed under directly.
+// errWrongSecret is returned if secret does not open the file.
+func decodeUser(file []byte, kind byte, secret string) (*User, int, error) {
+	hdr, sealed, err := parseVault(file)
 	if err != nil {
-		return fmt.Errorf("user.Serialize: %v", err)
+		return nil, 0, fmt.Errorf("parseVault: %v", err)
+	}
+	var passkey []byte
+	switch {
+	case hdr.version == vaultVersion:
+		var slot *keySlot
+		for _, s := range hdr.slots {
+			if s.kind == kind {
+				slot = s
+			}
+		}
+		if slot == nil {
+			return nil, 0, errWrongSecret
+		}
+		if passkey, err = slot.unwrap(secret); err == errWrongSecret {
+			return nil, 0, err
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("unwrap: %v", err)
+		}
+	case kind != slotPassword:
+		// Legacy files only open with a password.
+		return nil, 0, errWrongSecret
+	default:
+		passkey = generatePasskey(secret)
 	}
-
-	block, err := aes.NewCipher(passkey)
-	if err != nil {
-		return fmt.Errorf("aes.NewCipher: %v", err)
+	plain, err := unseal(passkey, sealed, hdr.aad)
+	if err != nil && hdr.version != vaultVersion {
+		// Without a key slot, only the body shows whether the password was right.
+		return nil, 0, errWrongSecret
 	}
-	aesgcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return fmt.Errorf("cipher.NewGCM: %v", err)
-	}
-	nonce := make([]byte, nonceLen)
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return fmt.Errorf("io.ReadFull(random): %v", err)
+		return nil, 0, fmt.Errorf("unseal: %v", err)
 	}
 
-	outFile, err := os.Create(saveFile)
+	usr, err := DeserializeUser(plain)
 	if err != nil {
-		return fmt.Errorf("os.Create(%s): %v", saveFile, err)
+		return nil, 0, fmt.Errorf("DeserializeUser: %v", err)
+	}
+	usr.passkey = passkey
+	for _, s := range hdr.slots {
+		switch s.kind {
+		case slotPassword:
+			usr.password = s
+		case slotRecovery:
+			usr.recovery = s
+		}
 	}
-	defer outFile.Close()
+	return usr, hdr.version, nil
+}
 
-	encrypted := aesgcm.Seal(nil, nonce, serUsr, nil)
+// encodeUser encrypts the User and returns their save file in the current vault format, along
+// with the serialized User it seals.
+func encodeUser(usr *User) ([]byte, []byte, error) {
+	slots := usr.keySlots()
+	if len(slots) == 0 || slots[0].kind != slotPassword {
+		return nil, nil, errors.New("user has no password key slot")
+	}
+	passkey := usr.dataKey()
 
-	if _, err := outFile.Write(nonce); err != nil {
-		return fmt.Errorf("outFile.Write: %v", err)
+	serUsr, err := usr.Serialize()
+	if err != nil {
+		return nil, nil, fmt.Errorf("user.Serialize: %v", err)
 	}
-	if _, err := outFile.Write(encrypted); err != nil {
-		return fmt.Errorf("outFile.Write: %v", err)
+	header := vaultHeader(slots...)
+	encrypted, err := seal(passkey, serUsr, header)
+	if err != nil {
+		return nil, nil, fmt.Errorf("seal: %v", err)
 	}
-	return nil
+	return append(header, encrypted...), serUsr, nil
 }
 
+// generatePasskey is the unsalted key derivation of legacy save files. It is only used to open
+// them once so that they can be upgraded.
 func generatePasskey(password string) []byte {
 	hash := sha256.Sum256([]byte(password))
 	passkey := make([]byte, aesKeyLen)
@@ -107,145 +156,297 @@ func generatePasskey(password string) []byte {
 	return passkey
 }
 
-// NewManager returns a new instance of Manager.
-func NewManager(saveDir string) *Manager {
-	return &Manager{
-		active:  make(map[string]*Session),
-		saveDir: saveDir,
+// newUser returns an empty User with a random data key, wrapped under password and under the
+// returned recovery code.
+func newUser(name, password string) (*User, string, error) {
+	dataKey, err := newDataKey()
+	if err != nil {
+		return nil, "", err
 	}
+	usr := &User{
+		Name:       name,
+		passkey:    dataKey,
+		manager:    rule.NewEmptyManager(),
+		categories: register.NewCategoryTree(),
+		payees:     payee.NewDirectory(nil),
+	}
+	if err := setPassword(usr, password); err != nil {
+		return nil, "", err
+	}
+	code, err := setRecoveryCode(usr)
+	if err != nil {
+		return nil, "", err
+	}
+	return usr, code, nil
+}
+
+// NewManager returns a new instance of Manager that keeps users in the given store. Sessions
+// expire after the default timeouts, and expired sessions are saved and evicted in the background
+// until the Manager is closed. Users with sessions are saved in the background shortly after they
+// change.
+func NewManager(store VaultStore) *Manager {
+	m := &Manager{
+		active:           make(map[string]*Session),
+		users:            make(map[string]*liveUser),
+		store:            store,
+		idleTimeout:      DefaultIdleTimeout,
+		sessionLifetime:  DefaultSessionLifetime,
+		snapshots:        DefaultSnapshots,
+		snapshotInterval: DefaultSnapshotInterval,
+		flush:            make(chan struct{}, 1),
+		done:             make(chan struct{}),
+	}
+	go m.reap(reapInterval)
+	go m.flusher(flushInterval, flushDelay)
+	return m
 }
 
 // Manager manages the user models and their active state.
 type Manager struct {
-	mu      sync.Mutex
-	saveDir string
-	active  map[string]*Session
+	mu     sync.Mutex
+	store  VaultStore
+	active map[string]*Session
+	// users holds the one live User of each name with a session.
+	users map[string]*liveUser
+
+	idleTimeout     time.Duration
+	sessionLifetime time.Duration
+	// snapshots is how many snapshots of each User are kept, taken at most every
+	// snapshotInterval. They have their own lock as users are saved while holding mu.
+	snapshotMu       sync.Mutex
+	snapshots        int
+	snapshotInterval time.Duration
+	// flush wakes the flusher when a User changes.
+	flush chan struct{}
+	// done stops the reaper and the flusher when closed.
+	done     chan struct{}
+	stopOnce sync.Once
 }
 
-func (m *Manager) userSaveFile(name string) string {
-	baseName := name + postfix
-	baseName = base64.URLEncoding.EncodeToString([]byte(baseName))
-	return filepath.Join(m.saveDir, baseName)
+// userKey is the store key of the named User's save file.
+func userKey(name string) string {
+	return base64.URLEncoding.EncodeToString([]byte(name + postfix))
+}
+
+// readUser opens the save file of the named User with the key slot of the given kind that secret
+// opens, and returns the vault version it was written with. ErrNoObject is returned if the User
+// has no save file, and errWrongSecret if secret does not open it.
+func (m *Manager) readUser(name string, kind byte, secret string) (*User, int, error) {
+	key := userKey(name)
+	file, stored, err := m.store.Get(key)
+	if err == ErrNoObject {
+		return nil, 0, err
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("store.Get(%s): %v", key, err)
+	}
+	usr, version, err := decodeUser(file, kind, secret)
+	if err == errWrongSecret {
+		return nil, 0, err
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("decodeUser(%s): %v", key, err)
+	}
+	usr.stored = stored
+	return usr, version, nil
+}
+
+// saveUser writes the given User to the store along with their calendar feed. The save file is
+// only replaced if it has not been written by anyone else since the User was read.
+func (m *Manager) saveUser(usr *User) error {
+	return m.writeUser(usr, false)
+}
+
+// writeUser saves the given User and snapshots the new save file, even if the last snapshot is
+// recent when force is set.
+func (m *Manager) writeUser(usr *User, force bool) error {
+	usr.saveMu.Lock()
+	defer usr.saveMu.Unlock()
+	// Changes made while the User is written mark them dirty again.
+	usr.setDirty(false)
+	file, plain, err := encodeUser(usr)
+	if err != nil {
+		usr.setDirty(true)
+		return fmt.Errorf("encodeUser: %v", err)
+	}
+	key := userKey(usr.Name)
+	stored, err := m.store.CompareAndSwap(key, usr.stored, file)
+	if err != nil {
+		usr.setDirty(true)
+		return fmt.Errorf("store.CompareAndSwap(%s): %v", key, err)
+	}
+	usr.stored = stored
+	if err := m.snapshot(usr, plain, force); err != nil {
+		return fmt.Errorf("snapshot: %v", err)
+	}
+	if err := m.writeCalendar(usr); err != nil {
+		return fmt.Errorf("writeCalendar: %v", err)
+	}
+	return nil
 }
 
 // ValidSession given a session token produced by Login(), returns the
-// associated User with that token value.
+// associated User with that token value. Nil is returned once the session has
+// been idle or open for too long, and the session is left for the reaper to
+// save and evict. Otherwise the session is marked as used.
 func (m *Manager) ValidSession(enc string) *User {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if sess, ok := m.active[enc]; ok {
-		return sess.User
+	sess, ok := m.active[enc]
+	if !ok {
+		return nil
 	}
-	return nil
+	now := time.Now()
+	if sess.expired(now, m.idleTimeout, m.sessionLifetime) {
+		return nil
+	}
+	sess.LastSeen = now
+	return sess.User
 }
 
 // Register creates a new User, so long as the given name is not being used by
-// any other User account. If it is, (nil, "", err) is returned, otherwise the
-// new User struct and a session token is returned with nil error.
-func (m *Manager) Register(name, password string) (*User, string, error) {
+// any other User account. If it is, (nil, "", "", err) is returned, otherwise the
+// new User struct, a session token and their one-time recovery code are returned
+// with nil error. The new User is saved before their session starts, and the
+// registration fails if they cannot be.
+func (m *Manager) Register(name, password string) (*User, string, string, error) {
 	if len(name) == 0 {
-		return nil, "", errors.New("Username must be at least 1 character long")
+		return nil, "", "", errors.New("Username must be at least 1 character long")
 	}
 	if len(password) < 4 {
-		return nil, "", errors.New("Password must be at least 4 characters long")
+		return nil, "", "", errors.New("Password must be at least 4 characters long")
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	errAlreadyExists := fmt.Errorf("There is already a user with name: %s", name)
-	saveFile := m.userSaveFile(name)
-	if _, err := os.Stat(saveFile); !os.IsNotExist(err) {
-		return nil, "", errAlreadyExists
+	if _, _, err := m.store.Get(userKey(name)); err != ErrNoObject {
+		return nil, "", "", errAlreadyExists
 	}
-	for _, sess := range m.active {
-		if activeName := sess.User.Name; name == activeName {
-			return nil, "", errAlreadyExists
-		}
+	m.mu.Lock()
+	_, ok := m.users[name]
+	m.mu.Unlock()
+	if ok {
+		return nil, "", "", errAlreadyExists
 	}
 
-	passkey := generatePasskey(password)
-	usr := &User{
-		Name:    name,
-		passkey: passkey,
-		manager: rule.NewEmptyManager(),
+	usr, code, err := newUser(name, password)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("newUser: %v", err)
+	}
+	// The save file is only created if there is none yet, so a concurrent registration of the
+	// same name fails here.
+	if err := m.saveUser(usr); err != nil {
+		if usr.stored == "" {
+			if _, _, getErr := m.store.Get(userKey(name)); getErr != ErrNoObject {
+				return nil, "", "", errAlreadyExists
+			}
+		}
+		return nil, "", "", fmt.Errorf("saveUser(%s): %v", name, err)
 	}
 
-	s := &Session{
-		Start: time.Now(),
-		User:  usr,
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	enc, err := m.newSession(usr)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("newSession: %v", err)
 	}
-	enc := s.String()
-	m.active[enc] = s
-	return usr, enc, nil
+	return usr, enc, code, nil
 }
 
 // Login given a user name and password, returns a non-nil User pointer and an
 // encoded session token when a valid User has logged in. Otherwise, (nil, "")
-// is returned.
-func (m *Manager) Login(name, password string) (*User, string, error) {
-	saveFile := m.userSaveFile(name)
-	if _, err := os.Stat(saveFile); os.IsNotExist(err) {
-		return nil, "", nil
+// is returned. Every session of a User shares the same User. Users whose save
+// file predates recovery codes are given one as it is upgraded, which is also
+// returned and must be shown to them now; it is empty otherwise.
+func (m *Manager) Login(name, password string) (*User, string, string, error) {
+	usr, code, err := m.loadUser(name, password)
+	if err != nil || usr == nil {
+		return nil, "", "", err
 	}
 
-	passkey := generatePasskey(password)
-
-	usr, err := decodeUser(saveFile, passkey)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Another login may have loaded the User in the meantime.
+	if live, ok := m.users[name]; ok {
+		usr = live.user
+	}
+	enc, err := m.newSession(usr)
 	if err != nil {
-		return nil, "", fmt.Errorf("decodeUser(%s): %v", saveFile, err)
+		return nil, "", "", fmt.Errorf("newSession: %v", err)
 	}
+	return usr, enc, code, nil
+}
 
-	s := &Session{
-		Start: time.Now(),
-		User:  usr,
-	}
-	enc := s.String()
+// loadUser returns the named User once password is verified: the live User if they have a
+// session, or else the User in their save file, upgraded to the current vault format. Nil is
+// returned if they have no save file. The recovery code set by an upgrade is returned too.
+func (m *Manager) loadUser(name, password string) (*User, string, error) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.active[enc] = s
-	return usr, enc, nil
+	live, ok := m.users[name]
+	m.mu.Unlock()
+	if ok {
+		// The live User may have changes that are not saved yet.
+		if err := live.user.checkPassword(password); err != nil {
+			return nil, "", fmt.Errorf("checkPassword(%s): %v", name, err)
+		}
+		return live.user, "", nil
+	}
+
+	usr, version, err := m.readUser(name, slotPassword, password)
+	if err == ErrNoObject {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("readUser: %v", err)
+	}
+	if version != vaultVersion {
+		// Move legacy files to envelope encryption now that the password is known.
+		code, err := m.rekey(usr, password)
+		if err != nil {
+			return nil, "", fmt.Errorf("rekey(%s): %v", name, err)
+		}
+		return usr, code, nil
+	}
+	if usr.password.kdf.weak() {
+		// Strengthen an outdated password key derivation.
+		if err := setPassword(usr, password); err != nil {
+			return nil, "", fmt.Errorf("setPassword(%s): %v", name, err)
+		}
+		if err := m.saveUser(usr); err != nil {
+			return nil, "", fmt.Errorf("saveUser(%s): %v", name, err)
+		}
+	}
+	if err := m.finishRekey(usr); err != nil {
+		return nil, "", fmt.Errorf("finishRekey(%s): %v", name, err)
+	}
+	return usr, "", nil
 }
 
-// Logout removes the given session token and serializes the user to disk.
+// Logout removes the given session token, and saves the user to the store if
+// it was their last session.
 func (m *Manager) Logout(enc string) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	sess, ok := m.active[enc]
-	if !ok {
-		return fmt.Errorf("no active session with: %s", enc)
-	}
-	defer delete(m.active, enc)
-
-	usr := sess.User
-	saveFile := m.userSaveFile(usr.Name)
-	if err := encodeUser(usr, saveFile); err != nil {
-		return fmt.Errorf("encodeUser: %v", err)
+	usr, err := m.endSession(enc)
+	m.mu.Unlock()
+	if err != nil || usr == nil {
+		return err
 	}
-	return nil
+	return m.unload(usr)
 }
 
-// Close removes all session state from memory, saving it to disk.
-// The Manager should not be used after calling Close().
+// Close removes all session state from memory, saving it to the store, and stops
+// the reaper and the flusher. The Manager should not be used after calling Close().
 func (m *Manager) Close() error {
+	m.stop()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	var errs []string
-	for _, sess := range m.active {
-		err := func() error {
-			usr := sess.User
-
-			saveFile := m.userSaveFile(usr.Name)
-			if err := encodeUser(usr, saveFile); err != nil {
-				return fmt.Errorf("encodeUser: %v", err)
-			}
-			return nil
-		}()
-		if err != nil {
+	for _, live := range m.users {
+		if err := m.saveUser(live.user); err != nil {
 			errs = append(errs, err.Error())
 		}
 	}
 	m.active = nil
+	m.users = nil
 	if l := len(errs); l > 0 {
 		return fmt.Errorf("%d errors closing manager: %s", l, strings.Join(errs, ", "))
 	}
@@ -254,25 +455,17 @@ func (m *Manager) Close() error {
 
 // CreateTestManager returns a Manager with a single valid user login.
 // username: test, password: test
+// The Manager keeps users in memory, and does not save or evict sessions in the
+// background, so that tests control when users are written.
 func CreateTestManager() (*Manager, error) {
-	saveDir := filepath.Join(os.TempDir(), "oyster-test")
-	if err := os.RemoveAll(saveDir); err != nil {
-		return nil, fmt.Errorf("os.RemoveAll(%s): %v", saveDir, err)
-	}
-	if err := os.Mkdir(saveDir, os.FileMode(0775)); err != nil {
-		return nil, fmt.Errorf("os.Mkdir(%s): %v", saveDir, err)
-	}
-
-	passkey := generatePasskey("test")
-
-	manager := NewManager(saveDir)
-	testUsr := &User{
-		Name:    "test",
-		passkey: passkey,
-		manager: rule.NewEmptyManager(),
+	manager := NewManager(NewMemoryStore())
+	manager.stop()
+	testUsr, _, err := newUser("test", "test")
+	if err != nil {
+		return nil, fmt.Errorf("newUser: %v", err)
 	}
-	if err := encodeUser(testUsr, manager.userSaveFile("test")); err != nil {
-		return nil, fmt.Errorf("encodeUser: %v", err)
+	if err := manager.saveUser(testUsr); err != nil {
+		return nil, fmt.Errorf("saveUser: %v", err)
 	}
 
 	return manager, nil
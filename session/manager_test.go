@@ -1,12 +1,11 @@
 package session
 
 import (
-	"os"
-	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/groggygopher/oyster/payee"
 	"github.com/groggygopher/oyster/register"
 	"github.com/groggygopher/oyster/rule"
 )
@@ -86,25 +85,31 @@ func TestRegister(t *testing.T) {
 			}
 
 			if len(test.saveUser) > 0 {
-				saveFile := m.userSaveFile(test.saveUser)
-				if _, err := os.Create(saveFile); err != nil {
-					t.Fatalf("os.Create(%s): %v", saveFile, err)
+				if _, err := m.store.Put(userKey(test.saveUser), nil); err != nil {
+					t.Fatalf("store.Put(%s): %v", test.saveUser, err)
 				}
 			}
 
 			if len(test.activeUser) > 0 {
+				usr := &User{
+					Name: test.activeUser,
+				}
 				m.active["test"] = &Session{
 					Start: time.Now(),
-					User: &User{
-						Name: test.activeUser,
-					},
+					User:  usr,
 				}
+				m.users[usr.Name] = &liveUser{user: usr, refs: 1}
 			}
 
-			_, _, err = m.Register(test.newUser, test.password)
+			_, _, _, err = m.Register(test.newUser, test.password)
 			if got, want := err != nil, test.wantErr; got != want {
 				t.Errorf("wantErr: err: %v, got: %t, want: %t", err, got, want)
 			}
+			if err == nil {
+				if _, _, err := m.store.Get(userKey(test.newUser)); err != nil {
+					t.Errorf("a registered User should be saved: store.Get: %v", err)
+				}
+			}
 		})
 	}
 }
@@ -116,10 +121,12 @@ func TestLogout(t *testing.T) {
 	}
 
 	m.active["test"] = &Session{
-		Start: time.Now(),
+		Start:    time.Now(),
+		LastSeen: time.Now(),
 		User: &User{
-			Name:    "test",
-			passkey: []byte("testtesttesttesttesttesttesttest"),
+			Name:     "logout",
+			passkey:  []byte("testtesttesttesttesttesttesttest"),
+			password: &keySlot{kind: slotPassword, kdf: &kdfParams{LogN: 1, R: 1, P: 1}},
 		},
 	}
 	if err := m.Logout("bad"); err == nil {
@@ -143,7 +150,8 @@ func TestValidSession(t *testing.T) {
 	}
 
 	m.active["test"] = &Session{
-		Start: time.Now(),
+		Start:    time.Now(),
+		LastSeen: time.Now(),
 		User: &User{
 			Name:    "test",
 			passkey: []byte("testtesttesttesttesttesttesttest"),
@@ -158,33 +166,37 @@ func TestValidSession(t *testing.T) {
 }
 
 func TestDecodeEncode(t *testing.T) {
-	passkey := []byte("testtesttesttesttesttesttesttest")
+	kdf := &kdfParams{LogN: 1, R: 1, P: 1, Salt: []byte("salt")}
+	dataKey := []byte("testtesttesttesttesttesttesttest")
+	slot, err := newKeySlot(slotPassword, kdf, "test", dataKey)
+	if err != nil {
+		t.Fatalf("newKeySlot: %v", err)
+	}
 	usr := &User{
-		Name:    "test",
-		passkey: passkey,
+		Name:     "test",
+		passkey:  dataKey,
+		password: slot,
 		transactions: []*register.Transaction{
 			&register.Transaction{
 				Description: "test",
+				Payee:       "Test",
 			},
 		},
-		manager: rule.NewEmptyManager(),
-	}
-	saveDir := filepath.Join(os.TempDir(), "oyster-test")
-	if err := os.RemoveAll(saveDir); err != nil {
-		t.Fatalf("os.RemoveAll(%s): %v", saveDir, err)
-	}
-	if err := os.Mkdir(saveDir, os.FileMode(0775)); err != nil {
-		t.Fatalf("os.Mkdir(%s): %v", saveDir, err)
+		manager:    rule.NewEmptyManager(),
+		categories: register.NewCategoryTree(),
+		payees:     payee.NewDirectory(nil),
 	}
-	saveFile := filepath.Join(saveDir, "test")
-
-	if err := encodeUser(usr, saveFile); err != nil {
+	file, _, err := encodeUser(usr)
+	if err != nil {
 		t.Fatalf("encodeUser: %v", err)
 	}
-	decUsr, err := decodeUser(saveFile, passkey)
+	decUsr, version, err := decodeUser(file, slotPassword, "test")
 	if err != nil {
 		t.Fatalf("decodeUser: %v", err)
 	}
+	if got, want := version, vaultVersion; got != want {
+		t.Errorf("version: got: %d, want: %d", got, want)
+	}
 
 	if got, want := decUsr, usr; !reflect.DeepEqual(got, want) {
 		t.Errorf("got: %v, want: %v", got, want)
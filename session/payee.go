@@ -0,0 +1,45 @@
+package session
+
+import (
+	"github.com/groggygopher/oyster/payee"
+)
+
+// PayeeAliases returns this User's payee alias table.
+func (u *User) PayeeAliases() []*payee.Alias {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.payees.Aliases()
+}
+
+// SetPayeeAlias adds or replaces a payee alias and renames the payee of every transaction.
+func (u *User) SetPayeeAlias(a *payee.Alias) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := u.payees.Set(a); err != nil {
+		return err
+	}
+	u.renamePayees()
+	u.touch()
+	return nil
+}
+
+// DeletePayeeAlias removes a payee alias, returning true if it existed, and renames the payee of
+// every transaction.
+func (u *User) DeletePayeeAlias(match string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.payees.Delete(match) {
+		return false
+	}
+	u.renamePayees()
+	u.touch()
+	return true
+}
+
+// renamePayees recomputes the payee of every transaction from its raw description. It must be
+// called while holding u.mu.
+func (u *User) renamePayees() {
+	for _, t := range u.transactions {
+		t.Payee = u.payees.Name(t.Description)
+	}
+}
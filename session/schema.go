@@ -0,0 +1,130 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/groggygopher/oyster/payee"
+	"github.com/groggygopher/oyster/register"
+)
+
+// userSchema is the schema version of the serialized User that Serialize writes. Whenever the
+// layout of serializeableUser changes in a way that older payloads don't decode into as they
+// are, bump it and append the migration from the previous version to migrations.
+const userSchema = 1
+
+// migrations upgrade older serialized Users step by step: migrations[v] turns a payload of
+// schema version v into one of version v+1. Payloads from before schema versions are version 0.
+var migrations = []func(userDoc) error{
+	migrateUnversioned,
+}
+
+// userDoc is a serialized User decoded just far enough to migrate it field by field, so that
+// migrations keep working as the model changes.
+type userDoc map[string]json.RawMessage
+
+// get decodes the named field into v, returning false if the field is missing or null.
+func (d userDoc) get(field string, v interface{}) (bool, error) {
+	raw, ok := d[field]
+	if !ok || string(raw) == "null" {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("json.Unmarshal(%s): %v", field, err)
+	}
+	return true, nil
+}
+
+// set encodes v as the named field.
+func (d userDoc) set(field string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("json.Marshal(%s): %v", field, err)
+	}
+	d[field] = raw
+	return nil
+}
+
+// upgrade migrates the payload from its schema version to userSchema. Payloads from a newer
+// version than this code knows are rejected rather than losing the fields it doesn't know.
+func (d userDoc) upgrade() error {
+	var version int
+	if _, err := d.get("Version", &version); err != nil {
+		return err
+	}
+	if version > userSchema {
+		return fmt.Errorf("schema version %d is newer than the supported version %d", version, userSchema)
+	}
+	for v := version; v < userSchema; v++ {
+		if err := migrations[v](d); err != nil {
+			return fmt.Errorf("migrate from schema version %d: %v", v, err)
+		}
+	}
+	return d.set("Version", userSchema)
+}
+
+// migrateUnversioned upgrades a User saved before schema versions. Users saved before categories
+// were managed adopt every category already in use, and transactions saved before payees were
+// normalized are given a payee.
+func migrateUnversioned(d userDoc) error {
+	var cats []string
+	ok, err := d.get("Categories", &cats)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		var trans []*register.Transaction
+		if _, err := d.get("Transactions", &trans); err != nil {
+			return err
+		}
+		var rules []struct {
+			Category string `json:"category"`
+		}
+		if _, err := d.get("Rules", &rules); err != nil {
+			return err
+		}
+		var ruleCats []string
+		for _, r := range rules {
+			ruleCats = append(ruleCats, r.Category)
+		}
+		if err := d.set("Categories", seedCategories(trans, ruleCats).Names()); err != nil {
+			return err
+		}
+	}
+
+	var aliases []*payee.Alias
+	if _, err := d.get("Payees", &aliases); err != nil {
+		return err
+	}
+	dir := payee.NewDirectory(aliases)
+	// Transactions are migrated as raw objects, so that fields unknown here are kept as they are.
+	var trans []map[string]json.RawMessage
+	if _, err := d.get("Transactions", &trans); err != nil {
+		return err
+	}
+	for _, t := range trans {
+		var name, desc string
+		if raw, ok := t["payee"]; ok {
+			if err := json.Unmarshal(raw, &name); err != nil {
+				return fmt.Errorf("json.Unmarshal(payee): %v", err)
+			}
+		}
+		if name != "" {
+			continue
+		}
+		if raw, ok := t["description"]; ok {
+			if err := json.Unmarshal(raw, &desc); err != nil {
+				return fmt.Errorf("json.Unmarshal(description): %v", err)
+			}
+		}
+		raw, err := json.Marshal(dir.Name(desc))
+		if err != nil {
+			return fmt.Errorf("json.Marshal(payee): %v", err)
+		}
+		t["payee"] = raw
+	}
+	if trans == nil {
+		return nil
+	}
+	return d.set("Transactions", trans)
+}
@@ -0,0 +1,144 @@
+package session
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readFixture returns the golden serialized User in testdata/schema/name, compressed the way
+// Serialize writes it.
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	file := filepath.Join("testdata", "schema", name)
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(%s): %v", file, err)
+	}
+	return gzipBytes(t, data)
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatalf("gzip.Write: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip.Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestMigrations(t *testing.T) {
+	if got, want := len(migrations), userSchema; got != want {
+		t.Fatalf("migrations: got: %d, want: one for every version before %d", got, want)
+	}
+}
+
+func TestDeserializeFixtures(t *testing.T) {
+	tests := []struct {
+		fixture    string
+		categories []string
+		payees     []string
+		check      func(*testing.T, *User)
+	}{
+		{
+			// Before categories and payees.
+			fixture:    "v0-baseline.json",
+			categories: []string{"Bills", "Bills:Rent", "Food", "Food:Groceries"},
+			payees:     []string{"Safeway", "Property Mgmt"},
+			check: func(t *testing.T, usr *User) {
+				if got, want := len(usr.manager.Rules()), 1; got != want {
+					t.Errorf("rules: got: %d, want: %d", got, want)
+				}
+			},
+		},
+		{
+			// Managed categories, schedules and accounts, before payees.
+			fixture:    "v0-categories.json",
+			categories: []string{"Food", "Food:Groceries", "Travel"},
+			payees:     []string{"Safeway"},
+			check: func(t *testing.T, usr *User) {
+				if got, want := len(usr.schedules), 1; got != want {
+					t.Errorf("schedules: got: %d, want: %d", got, want)
+				}
+				if got, want := len(usr.accounts), 1; got != want {
+					t.Errorf("accounts: got: %d, want: %d", got, want)
+				}
+				if got, want := usr.transactions[0].Tags, []string{"weekly"}; !reflect.DeepEqual(got, want) {
+					t.Errorf("tags: got: %v, want: %v", got, want)
+				}
+			},
+		},
+		{
+			// Payee aliases, dismissed duplicates and import batches.
+			fixture:    "v0-payees.json",
+			categories: []string{"Food", "Food:Groceries"},
+			payees:     []string{"Safeway", "Blue Bottle"},
+			check: func(t *testing.T, usr *User) {
+				if got, want := usr.dismissed, []string{"t1|t2"}; !reflect.DeepEqual(got, want) {
+					t.Errorf("dismissed: got: %v, want: %v", got, want)
+				}
+				if got, want := len(usr.imports), 1; got != want {
+					t.Errorf("imports: got: %d, want: %d", got, want)
+				}
+			},
+		},
+		{
+			// Current payloads are not migrated.
+			fixture: "v1.json",
+			payees:  []string{""},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			usr, err := DeserializeUser(readFixture(t, test.fixture))
+			if err != nil {
+				t.Fatalf("DeserializeUser: %v", err)
+			}
+			if got, want := usr.Name, "golden"; got != want {
+				t.Errorf("name: got: %s, want: %s", got, want)
+			}
+			if got, want := usr.categories.Names(), test.categories; !reflect.DeepEqual(got, want) {
+				t.Errorf("categories: got: %v, want: %v", got, want)
+			}
+			var payees []string
+			for _, tr := range usr.transactions {
+				payees = append(payees, tr.Payee)
+			}
+			if got, want := payees, test.payees; !reflect.DeepEqual(got, want) {
+				t.Errorf("payees: got: %v, want: %v", got, want)
+			}
+			if test.check != nil {
+				test.check(t, usr)
+			}
+
+			// A migrated User is saved at the current version and reads back the same.
+			bs, err := usr.Serialize()
+			if err != nil {
+				t.Fatalf("Serialize: %v", err)
+			}
+			again, err := DeserializeUser(bs)
+			if err != nil {
+				t.Fatalf("DeserializeUser again: %v", err)
+			}
+			if got, want := again.categories.Names(), usr.categories.Names(); !reflect.DeepEqual(got, want) {
+				t.Errorf("categories again: got: %v, want: %v", got, want)
+			}
+			if got, want := again.transactions, usr.transactions; !reflect.DeepEqual(got, want) {
+				t.Errorf("transactions again: got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDeserializeNewerSchema(t *testing.T) {
+	if _, err := DeserializeUser(gzipBytes(t, []byte(`{"Version": 2, "Name": "future"}`))); err == nil {
+		t.Error("DeserializeUser of a newer schema version: expected non-nil error")
+	}
+}
@@ -0,0 +1,309 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultIdleTimeout ends a session that has not been used for this long.
+	DefaultIdleTimeout = 30 * time.Minute
+	// DefaultSessionLifetime ends a session this long after login, however much it is used.
+	DefaultSessionLifetime = 12 * time.Hour
+
+	// reapInterval is how often expired sessions are saved and evicted.
+	reapInterval = time.Minute
+	// flushInterval is how often changed users are saved, at the latest. flushDelay is how long
+	// the flusher waits after a change, so that a burst of changes is saved at once.
+	flushInterval = 30 * time.Second
+	flushDelay    = time.Second
+	// tokenLen is the number of random bytes in a session token.
+	tokenLen = 32
+)
+
+// ErrNoSession is returned when a User has no session with a given ID.
+var ErrNoSession = errors.New("no such session")
+
+// Session is a data model containing the user session state. ID identifies the session to its
+// User without revealing its token.
+type Session struct {
+	ID       string    `json:"id"`
+	Start    time.Time `json:"start"`
+	LastSeen time.Time `json:"lastSeen"`
+	User     *User     `json:"user"`
+}
+
+// SessionInfo describes one of a User's sessions. Current is set for the session it was requested
+// with.
+type SessionInfo struct {
+	ID       string    `json:"id"`
+	Start    time.Time `json:"start"`
+	LastSeen time.Time `json:"lastSeen"`
+	Current  bool      `json:"current"`
+}
+
+// liveUser is a User shared by all of their sessions, along with the number of sessions.
+type liveUser struct {
+	user *User
+	refs int
+}
+
+// expired returns true if this Session has been idle for longer than idle, or began longer than
+// lifetime before now.
+func (s *Session) expired(now time.Time, idle, lifetime time.Duration) bool {
+	return now.Sub(s.LastSeen) > idle || now.Sub(s.Start) > lifetime
+}
+
+// newToken returns a random, opaque session token.
+func newToken() (string, error) {
+	raw := make([]byte, tokenLen)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return "", fmt.Errorf("io.ReadFull(random): %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// newSession starts a session for the given User and returns its token. The User becomes the live
+// User of their name unless there already is one, which the session shares instead. It must be
+// called while holding m.mu.
+func (m *Manager) newSession(usr *User) (string, error) {
+	enc, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	id, err := randomID("SESS-")
+	if err != nil {
+		return "", err
+	}
+	live, ok := m.users[usr.Name]
+	if !ok {
+		live = &liveUser{user: usr}
+		m.users[usr.Name] = live
+	}
+	live.refs++
+	usr = live.user
+
+	usr.mu.Lock()
+	usr.flush = m.flush
+	usr.mu.Unlock()
+	now := time.Now()
+	m.active[enc] = &Session{
+		ID:       id,
+		Start:    now,
+		LastSeen: now,
+		User:     usr,
+	}
+	return enc, nil
+}
+
+// endSession ends the session with the given token. When it was the last session of its User, the
+// User is returned so that the caller can unload them once m.mu is released. Until then they stay
+// live, so that a login in the meantime shares them. It must be called while holding m.mu.
+func (m *Manager) endSession(enc string) (*User, error) {
+	sess, ok := m.active[enc]
+	if !ok {
+		return nil, fmt.Errorf("no active session with: %s", enc)
+	}
+	delete(m.active, enc)
+	if live, ok := m.users[sess.User.Name]; ok {
+		if live.refs--; live.refs > 0 {
+			return nil, nil
+		}
+	}
+	return sess.User, nil
+}
+
+// unload saves the given User and, if they still have no session, removes them from memory. A User
+// who cannot be saved stays live, and the flusher tries again. It must not be called while holding
+// m.mu.
+func (m *Manager) unload(usr *User) error {
+	if err := m.saveUser(usr); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if live, ok := m.users[usr.Name]; ok && live.user == usr && live.refs == 0 {
+		delete(m.users, usr.Name)
+	}
+	return nil
+}
+
+// restartSessions ends every session of the given live User and returns a new one in their place,
+// so that anyone else logged in as them must log in again. The User stays loaded. It must be called
+// while holding m.mu.
+func (m *Manager) restartSessions(usr *User) (string, error) {
+	for enc, sess := range m.active {
+		if sess.User.Name == usr.Name {
+			delete(m.active, enc)
+		}
+	}
+	if live, ok := m.users[usr.Name]; ok {
+		live.refs = 0
+	}
+	return m.newSession(usr)
+}
+
+// Sessions returns the sessions of the User with the session enc, oldest first.
+func (m *Manager) Sessions(enc string) []*SessionInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, ok := m.active[enc]
+	if !ok {
+		return nil
+	}
+	var infos []*SessionInfo
+	for _, sess := range m.active {
+		if sess.User != current.User {
+			continue
+		}
+		infos = append(infos, &SessionInfo{
+			ID:       sess.ID,
+			Start:    sess.Start,
+			LastSeen: sess.LastSeen,
+			Current:  sess == current,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Start.Before(infos[j].Start)
+	})
+	return infos
+}
+
+// RevokeSession ends the session with the given ID of the User with the session enc, which may be
+// enc itself. ErrNoSession is returned if the User has no session with that ID.
+func (m *Manager) RevokeSession(enc, id string) error {
+	m.mu.Lock()
+	current, ok := m.active[enc]
+	if !ok {
+		m.mu.Unlock()
+		return ErrNoSession
+	}
+	for tok, sess := range m.active {
+		if sess.User == current.User && sess.ID == id {
+			usr, err := m.endSession(tok)
+			m.mu.Unlock()
+			if err != nil || usr == nil {
+				return err
+			}
+			return m.unload(usr)
+		}
+	}
+	m.mu.Unlock()
+	return ErrNoSession
+}
+
+// SetSessionTimeouts sets how long a session may be idle and how long it may last in total.
+func (m *Manager) SetSessionTimeouts(idle, lifetime time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.idleTimeout = idle
+	m.sessionLifetime = lifetime
+}
+
+// stop stops the reaper and the flusher.
+func (m *Manager) stop() {
+	m.stopOnce.Do(func() {
+		close(m.done)
+	})
+}
+
+// reap saves and evicts expired sessions every interval until m.done is closed.
+func (m *Manager) reap(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			if err := m.reapExpired(now); err != nil {
+				log.Printf("error: reapExpired: %v", err)
+			}
+		}
+	}
+}
+
+// reapExpired ends every session that has expired by now, and unloads Users whose last session it
+// was once m.mu is released.
+func (m *Manager) reapExpired(now time.Time) error {
+	m.mu.Lock()
+	var idle []*User
+	for enc, sess := range m.active {
+		if !sess.expired(now, m.idleTimeout, m.sessionLifetime) {
+			continue
+		}
+		if usr, _ := m.endSession(enc); usr != nil {
+			idle = append(idle, usr)
+		}
+	}
+	m.mu.Unlock()
+
+	var firstErr error
+	for _, usr := range idle {
+		if err := m.unload(usr); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("unload(%s): %v", usr.Name, err)
+		}
+	}
+	return firstErr
+}
+
+// flusher saves changed users every interval, and delay after any change, until m.done is closed.
+func (m *Manager) flusher(interval, delay time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+		case <-m.flush:
+			select {
+			case <-m.done:
+				return
+			case <-time.After(delay):
+			}
+		}
+		if err := m.flushDirty(); err != nil {
+			log.Printf("error: flushDirty: %v", err)
+		}
+	}
+}
+
+// flushDirty saves every live User who has changed since they were last saved, and unloads live
+// Users left without a session by an earlier failed save. The saves happen once m.mu is released.
+func (m *Manager) flushDirty() error {
+	m.mu.Lock()
+	var dirty, idle []*User
+	for _, live := range m.users {
+		switch {
+		case live.refs == 0:
+			idle = append(idle, live.user)
+		case live.user.isDirty():
+			dirty = append(dirty, live.user)
+		}
+	}
+	m.mu.Unlock()
+
+	var errs []string
+	for _, usr := range dirty {
+		if err := m.saveUser(usr); err != nil {
+			errs = append(errs, fmt.Sprintf("saveUser(%s): %v", usr.Name, err))
+		}
+	}
+	for _, usr := range idle {
+		if err := m.unload(usr); err != nil {
+			errs = append(errs, fmt.Sprintf("unload(%s): %v", usr.Name, err))
+		}
+	}
+	if l := len(errs); l > 0 {
+		return fmt.Errorf("%d errors flushing users: %s", l, strings.Join(errs, ", "))
+	}
+	return nil
+}
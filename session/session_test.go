@@ -0,0 +1,305 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidSessionTimeouts(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		label    string
+		start    time.Time
+		lastSeen time.Time
+		want     bool
+	}{
+		{
+			label:    "fresh",
+			start:    now.Add(-time.Hour),
+			lastSeen: now.Add(-time.Minute),
+			want:     true,
+		},
+		{
+			label:    "idle",
+			start:    now.Add(-time.Hour),
+			lastSeen: now.Add(-DefaultIdleTimeout - time.Minute),
+		},
+		{
+			label:    "too old",
+			start:    now.Add(-DefaultSessionLifetime - time.Minute),
+			lastSeen: now.Add(-time.Minute),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			m, err := CreateTestManager()
+			if err != nil {
+				t.Fatalf("CreateTestManager: %v", err)
+			}
+			m.active["test"] = &Session{
+				Start:    test.start,
+				LastSeen: test.lastSeen,
+				User:     &User{Name: "test"},
+			}
+			if got, want := m.ValidSession("test") != nil, test.want; got != want {
+				t.Errorf("valid: got: %t, want: %t", got, want)
+			}
+		})
+	}
+}
+
+func TestSessionTokens(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	_, a, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	_, b, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if a == b {
+		t.Errorf("tokens should be unique: got: %s twice", a)
+	}
+	if got, want := len(a), 43; got != want {
+		t.Errorf("token length: got: %d, want: %d", got, want)
+	}
+}
+
+func TestReapExpired(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	usr, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if err := usr.AddCategory("Reaped"); err != nil {
+		t.Fatalf("AddCategory: %v", err)
+	}
+
+	if err := m.reapExpired(time.Now()); err != nil {
+		t.Fatalf("reapExpired: %v", err)
+	}
+	if m.ValidSession(token) == nil {
+		t.Fatal("a fresh session should not be reaped")
+	}
+	if err := m.reapExpired(time.Now().Add(DefaultIdleTimeout + time.Minute)); err != nil {
+		t.Fatalf("reapExpired: %v", err)
+	}
+	if _, ok := m.active[token]; ok {
+		t.Fatal("an idle session should be evicted")
+	}
+
+	// The reaper saved the User before evicting them.
+	loggedIn, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !loggedIn.HasCategory("Reaped") {
+		t.Error("changes should be saved when a session is reaped")
+	}
+}
+
+func TestLogoutUnsavedUser(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	usr, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if err := usr.AddCategory("Unsaved"); err != nil {
+		t.Fatalf("AddCategory: %v", err)
+	}
+	// Saving the User conflicts while they hold a stale version of their save file.
+	stored := usr.stored
+	usr.stored = "stale"
+	if err := m.Logout(token); err == nil {
+		t.Fatal("Logout should fail when the User cannot be saved")
+	}
+	live, ok := m.users["test"]
+	if !ok || live.user != usr {
+		t.Fatal("a User who could not be saved should stay loaded")
+	}
+	if !usr.isDirty() {
+		t.Error("a User who could not be saved should stay dirty")
+	}
+	if err := m.flushDirty(); err == nil {
+		t.Error("flushDirty should retry saving the User")
+	}
+
+	// Once the conflict is resolved, the flusher saves and unloads the User.
+	usr.stored = stored
+	if err := m.flushDirty(); err != nil {
+		t.Fatalf("flushDirty: %v", err)
+	}
+	if _, ok := m.users["test"]; ok {
+		t.Error("flushDirty should unload a saved User without sessions")
+	}
+	loggedIn, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !loggedIn.HasCategory("Unsaved") {
+		t.Error("the retried save should be on disk")
+	}
+}
+
+func TestFlushDirty(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	usr, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if usr.isDirty() {
+		t.Fatal("a User should not be dirty after login")
+	}
+	if err := usr.AddCategory("Flushed"); err != nil {
+		t.Fatalf("AddCategory: %v", err)
+	}
+	if !usr.isDirty() {
+		t.Fatal("AddCategory should mark the User dirty")
+	}
+	select {
+	case <-m.flush:
+	default:
+		t.Error("AddCategory should wake the flusher")
+	}
+
+	if err := m.flushDirty(); err != nil {
+		t.Fatalf("flushDirty: %v", err)
+	}
+	if usr.isDirty() {
+		t.Error("flushDirty should save dirty users")
+	}
+	loggedIn, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !loggedIn.HasCategory("Flushed") {
+		t.Error("flushed changes should be on disk")
+	}
+}
+
+func TestFlusher(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	usr, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	m.done = make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		m.flusher(time.Hour, time.Millisecond)
+		close(stopped)
+	}()
+
+	usr.MarkDirty()
+	deadline := time.Now().Add(5 * time.Second)
+	for usr.isDirty() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if usr.isDirty() {
+		t.Error("the flusher should save a User soon after a change")
+	}
+	close(m.done)
+	<-stopped
+}
+
+func TestSharedUser(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	a, tokenA, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	b, tokenB, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if a != b {
+		t.Fatal("sessions of the same user should share one User")
+	}
+	if _, _, _, err := m.Login("test", "wrong"); err == nil {
+		t.Error("Login of a live User should check the password")
+	}
+
+	if err := a.AddCategory("Shared"); err != nil {
+		t.Fatalf("AddCategory: %v", err)
+	}
+	if err := m.Logout(tokenA); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if !a.isDirty() {
+		t.Error("the User should not be saved while they have another session")
+	}
+	if err := m.Logout(tokenB); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, ok := m.users["test"]; ok {
+		t.Error("the User should be unloaded after their last session")
+	}
+	loggedIn, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if loggedIn == a || !loggedIn.HasCategory("Shared") {
+		t.Error("the User should be saved after their last session and read back from disk")
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	_, first, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	_, second, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if _, _, _, err := m.Register("other", "other"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	infos := m.Sessions(second)
+	if got, want := len(infos), 2; got != want {
+		t.Fatalf("sessions: got: %d, want: %d", got, want)
+	}
+	if infos[0].Current || !infos[1].Current {
+		t.Errorf("current: got: %t, %t, want: false, true", infos[0].Current, infos[1].Current)
+	}
+
+	if got, want := m.RevokeSession(second, "SESS-missing"), ErrNoSession; got != want {
+		t.Errorf("RevokeSession of a missing session: got: %v, want: %v", got, want)
+	}
+	if err := m.RevokeSession(second, infos[0].ID); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if m.ValidSession(first) != nil {
+		t.Error("a revoked session should end")
+	}
+	if m.ValidSession(second) == nil {
+		t.Error("revoking another session should keep the current one")
+	}
+	if got, want := len(m.Sessions(second)), 1; got != want {
+		t.Errorf("sessions after revoke: got: %d, want: %d", got, want)
+	}
+}
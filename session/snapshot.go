@@ -0,0 +1,280 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/groggygopher/oyster/register"
+)
+
+const (
+	snapshotPostfix = "-snapshots"
+	snapshotPrefix  = "SNAP-"
+	// snapshotLayout names snapshots by the time they were taken, so that they sort by age.
+	snapshotLayout = "20060102T150405.000000000Z"
+	// DefaultSnapshots is how many snapshots of each User are kept.
+	DefaultSnapshots = 20
+	// DefaultSnapshotInterval is how long after a snapshot the next save takes another, so that
+	// autosaves don't push every older snapshot out within minutes.
+	DefaultSnapshotInterval = 15 * time.Minute
+)
+
+// ErrNoSnapshot is returned when a User has no snapshot with the requested ID.
+var ErrNoSnapshot = errors.New("no such snapshot")
+
+// Snapshot is an encrypted copy of a User's data as it was at Time. Snapshots are sealed under the
+// User's data key without the key slots of their save file, so that a password or recovery code
+// that no longer opens the save file doesn't open its snapshots either.
+type Snapshot struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+}
+
+// SnapshotSummary describes what a snapshot holds, so that it can be checked before it is
+// restored.
+type SnapshotSummary struct {
+	Snapshot
+	Transactions int        `json:"transactions"`
+	Latest       *time.Time `json:"latest"`
+	Categories   int        `json:"categories"`
+	Rules        int        `json:"rules"`
+	Accounts     int        `json:"accounts"`
+	Budgets      int        `json:"budgets"`
+	Schedules    int        `json:"schedules"`
+	Imports      int        `json:"imports"`
+}
+
+// snapshotKeyPrefix is the store key prefix of the named User's snapshots.
+func snapshotKeyPrefix(name string) string {
+	return userKey(name) + snapshotPostfix + "/"
+}
+
+// parseSnapshot returns the Snapshot stored under key, or nil if key is not a snapshot.
+func parseSnapshot(key string) *Snapshot {
+	id := key[strings.LastIndex(key, "/")+1:]
+	if !strings.HasPrefix(id, snapshotPrefix) {
+		return nil
+	}
+	t, err := time.Parse(snapshotLayout, strings.TrimPrefix(id, snapshotPrefix))
+	if err != nil {
+		return nil
+	}
+	return &Snapshot{ID: id, Time: t}
+}
+
+// SetSnapshots sets how many snapshots of each User are kept, and how long after a snapshot the
+// next save takes another. No snapshots are taken if keep is not positive.
+func (m *Manager) SetSnapshots(keep int, interval time.Duration) {
+	m.snapshotMu.Lock()
+	defer m.snapshotMu.Unlock()
+	m.snapshots = keep
+	m.snapshotInterval = interval
+}
+
+func (m *Manager) snapshotSettings() (int, time.Duration) {
+	m.snapshotMu.Lock()
+	defer m.snapshotMu.Unlock()
+	return m.snapshots, m.snapshotInterval
+}
+
+// listSnapshots returns the named User's snapshots, oldest first.
+func (m *Manager) listSnapshots(name string) ([]*Snapshot, error) {
+	prefix := snapshotKeyPrefix(name)
+	keys, err := m.store.List(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("store.List(%s): %v", prefix, err)
+	}
+	var snaps []*Snapshot
+	for _, key := range keys {
+		if s := parseSnapshot(key); s != nil {
+			snaps = append(snaps, s)
+		}
+	}
+	return snaps, nil
+}
+
+// snapshot seals a copy of the given User, serialized as plain, unless their latest snapshot is
+// more recent than the snapshot interval and force is false, and discards their oldest snapshots
+// beyond the number kept. It must be called while holding usr.saveMu.
+func (m *Manager) snapshot(usr *User, plain []byte, force bool) error {
+	keep, interval := m.snapshotSettings()
+	if keep <= 0 {
+		return nil
+	}
+	snaps, err := m.listSnapshots(usr.Name)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if l := len(snaps); l > 0 && !force && now.Sub(snaps[l-1].Time) < interval {
+		return nil
+	}
+	key := snapshotKeyPrefix(usr.Name) + snapshotPrefix + now.Format(snapshotLayout)
+	passkey := usr.dataKey()
+	sealed, err := seal(passkey, plain, []byte(key))
+	if err != nil {
+		return fmt.Errorf("seal: %v", err)
+	}
+	if _, err := m.store.Put(key, sealed); err != nil {
+		return fmt.Errorf("store.Put(%s): %v", key, err)
+	}
+	for l := len(snaps) + 1; l > keep; l-- {
+		key := snapshotKeyPrefix(usr.Name) + snaps[0].ID
+		if err := m.store.Delete(key); err != nil {
+			return fmt.Errorf("store.Delete(%s): %v", key, err)
+		}
+		snaps = snaps[1:]
+	}
+	return nil
+}
+
+// Snapshots returns the given User's snapshots, newest first.
+func (m *Manager) Snapshots(usr *User) ([]*Snapshot, error) {
+	snaps, err := m.listSnapshots(usr.Name)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(snaps)-1; i < j; i, j = i+1, j-1 {
+		snaps[i], snaps[j] = snaps[j], snaps[i]
+	}
+	return snaps, nil
+}
+
+// openSnapshot decrypts the given User's snapshot with the given ID. Snapshots are sealed under the
+// User's data key, so they open even after the User's password has changed, and are bound to their
+// store key so that they can't be swapped.
+func (m *Manager) openSnapshot(usr *User, id string) (*Snapshot, *User, error) {
+	snap := parseSnapshot(id)
+	if snap == nil || snap.ID != id {
+		return nil, nil, ErrNoSnapshot
+	}
+	key := snapshotKeyPrefix(usr.Name) + id
+	sealed, _, err := m.store.Get(key)
+	if err == ErrNoObject {
+		return nil, nil, ErrNoSnapshot
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("store.Get(%s): %v", key, err)
+	}
+	passkey := usr.dataKey()
+	plain, err := unseal(passkey, sealed, []byte(key))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unseal(%s): %v", id, err)
+	}
+	old, err := DeserializeUser(plain)
+	if err != nil {
+		return nil, nil, fmt.Errorf("DeserializeUser(%s): %v", id, err)
+	}
+	return snap, old, nil
+}
+
+// PreviewSnapshot summarizes the given User's snapshot with the given ID without restoring it.
+func (m *Manager) PreviewSnapshot(usr *User, id string) (*SnapshotSummary, error) {
+	snap, old, err := m.openSnapshot(usr, id)
+	if err != nil {
+		return nil, err
+	}
+	sum := &SnapshotSummary{
+		Snapshot:     *snap,
+		Transactions: len(old.transactions),
+		Categories:   len(old.categories.Names()),
+		Rules:        len(old.manager.Rules()),
+		Accounts:     len(old.accounts),
+		Budgets:      len(old.budgets),
+		Schedules:    len(old.schedules),
+		Imports:      len(old.imports),
+	}
+	for _, t := range old.transactions {
+		if t.Date != nil && (sum.Latest == nil || t.Date.After(*sum.Latest)) {
+			sum.Latest = t.Date
+		}
+	}
+	return sum, nil
+}
+
+// RestoreSnapshot replaces the given User's data with that of their snapshot with the given ID,
+// and saves them. The current state is snapshotted first, so a restore can itself be undone. The
+// User's password, recovery code and calendar token are kept as they are now, and attachments
+// deleted since the snapshot was taken stay deleted, so they are dropped from the restored
+// transactions. Attachments added since then are deleted once the restored data is saved. If that
+// save fails, the User's data is put back as it was before the restore.
+func (m *Manager) RestoreSnapshot(usr *User, id string) error {
+	_, old, err := m.openSnapshot(usr, id)
+	if err != nil {
+		return err
+	}
+	restored := make(map[string]bool)
+	for _, t := range old.transactions {
+		var kept []*register.Attachment
+		for _, a := range t.Attachments {
+			key := attachmentKey(usr.Name, a.ID)
+			_, _, err := m.store.Get(key)
+			if err == ErrNoObject {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("store.Get(%s): %v", key, err)
+			}
+			kept = append(kept, a)
+			restored[a.ID] = true
+		}
+		t.Attachments = kept
+	}
+	if err := m.writeUser(usr, true); err != nil {
+		return fmt.Errorf("writeUser(%s): %v", usr.Name, err)
+	}
+
+	usr.mu.Lock()
+	current := &User{
+		transactions: usr.transactions,
+		manager:      usr.manager,
+		categories:   usr.categories,
+		schedules:    usr.schedules,
+		accounts:     usr.accounts,
+		budgets:      usr.budgets,
+		payees:       usr.payees,
+		dismissed:    usr.dismissed,
+		merged:       usr.merged,
+		imports:      usr.imports,
+	}
+	usr.setData(old)
+	usr.mu.Unlock()
+
+	if err := m.saveUser(usr); err != nil {
+		usr.mu.Lock()
+		usr.setData(current)
+		usr.mu.Unlock()
+		return fmt.Errorf("saveUser(%s): %v", usr.Name, err)
+	}
+	for _, t := range current.transactions {
+		for _, a := range t.Attachments {
+			if restored[a.ID] {
+				continue
+			}
+			key := attachmentKey(usr.Name, a.ID)
+			if err := m.store.Delete(key); err != nil {
+				return fmt.Errorf("store.Delete(%s): %v", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setData replaces this User's data with that of from, leaving their keys and calendar token as
+// they are, and marks them changed. It must be called while holding u.mu.
+func (u *User) setData(from *User) {
+	u.transactions = from.transactions
+	u.manager = from.manager
+	u.categories = from.categories
+	u.schedules = from.schedules
+	u.accounts = from.accounts
+	u.budgets = from.budgets
+	u.payees = from.payees
+	u.dismissed = from.dismissed
+	u.merged = from.merged
+	u.imports = from.imports
+	u.ids = nil
+	u.touch()
+}
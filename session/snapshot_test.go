@@ -0,0 +1,211 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/groggygopher/oyster/register"
+)
+
+func TestSnapshots(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	m.SetSnapshots(3, time.Hour)
+	usr, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	snaps, err := m.Snapshots(usr)
+	if err != nil {
+		t.Fatalf("Snapshots: %v", err)
+	}
+	if got, want := len(snaps), 1; got != want {
+		t.Fatalf("snapshots of a new user: got: %d, want: %d", got, want)
+	}
+
+	if err := usr.AddCategory("Keep"); err != nil {
+		t.Fatalf("AddCategory: %v", err)
+	}
+	if err := m.writeUser(usr, true); err != nil {
+		t.Fatalf("writeUser: %v", err)
+	}
+	// A bad change saved within the snapshot interval does not replace the good snapshot.
+	if err := usr.AddCategory("Bad"); err != nil {
+		t.Fatalf("AddCategory: %v", err)
+	}
+	if err := m.saveUser(usr); err != nil {
+		t.Fatalf("saveUser: %v", err)
+	}
+	snaps, err = m.Snapshots(usr)
+	if err != nil {
+		t.Fatalf("Snapshots: %v", err)
+	}
+	if got, want := len(snaps), 2; got != want {
+		t.Fatalf("snapshots: got: %d, want: %d", got, want)
+	}
+	good := snaps[0]
+
+	sum, err := m.PreviewSnapshot(usr, good.ID)
+	if err != nil {
+		t.Fatalf("PreviewSnapshot: %v", err)
+	}
+	if got, want := sum.Categories, 1; got != want {
+		t.Errorf("preview categories: got: %d, want: %d", got, want)
+	}
+	if got, want := sum.Time, good.Time; !got.Equal(want) {
+		t.Errorf("preview time: got: %v, want: %v", got, want)
+	}
+
+	if err := m.RestoreSnapshot(usr, good.ID); err != nil {
+		t.Fatalf("RestoreSnapshot: %v", err)
+	}
+	if !usr.HasCategory("Keep") || usr.HasCategory("Bad") {
+		t.Errorf("categories after restore: got: %v, want: [Keep]", usr.categories.Names())
+	}
+	// The state before the restore is kept as the newest snapshot, and the oldest are discarded.
+	snaps, err = m.Snapshots(usr)
+	if err != nil {
+		t.Fatalf("Snapshots: %v", err)
+	}
+	if got, want := len(snaps), 3; got != want {
+		t.Fatalf("snapshots after restore: got: %d, want: %d", got, want)
+	}
+	if err := m.RestoreSnapshot(usr, snaps[0].ID); err != nil {
+		t.Fatalf("RestoreSnapshot: %v", err)
+	}
+	if !usr.HasCategory("Bad") {
+		t.Error("restoring the newest snapshot should undo the restore")
+	}
+	snaps, err = m.Snapshots(usr)
+	if err != nil {
+		t.Fatalf("Snapshots: %v", err)
+	}
+	if got, want := len(snaps), 3; got != want {
+		t.Errorf("snapshots kept: got: %d, want: %d", got, want)
+	}
+
+	// The restored User is saved.
+	loaded, _, err := m.readUser("test", slotPassword, "test")
+	if err != nil {
+		t.Fatalf("readUser: %v", err)
+	}
+	if !loaded.HasCategory("Bad") {
+		t.Error("the restored user should be saved")
+	}
+
+	for _, id := range []string{"", "SNAP-missing", "SNAP-20190101T000000.000000000Z", "../" + good.ID} {
+		if _, err := m.PreviewSnapshot(usr, id); err != ErrNoSnapshot {
+			t.Errorf("PreviewSnapshot(%q): got: %v, want: %v", id, err, ErrNoSnapshot)
+		}
+	}
+}
+
+func TestSnapshotsAfterRecovery(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	m.SetSnapshots(10, 0)
+	usr, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	code, err := m.NewRecoveryCode(usr)
+	if err != nil {
+		t.Fatalf("NewRecoveryCode: %v", err)
+	}
+	if err := m.saveUser(usr); err != nil {
+		t.Fatalf("saveUser: %v", err)
+	}
+	if recovered, _, _, err := m.Recover("test", code, "changed"); err != nil || recovered == nil {
+		t.Fatalf("Recover: got: %v, %v", recovered, err)
+	}
+
+	// Neither the old password nor the used code opens the save file or any snapshot.
+	keys, err := m.store.List(userKey("test"))
+	if err != nil {
+		t.Fatalf("store.List: %v", err)
+	}
+	var snapshots int
+	for _, key := range keys {
+		if parseSnapshot(key) != nil {
+			snapshots++
+		}
+		file, _, err := m.store.Get(key)
+		if err != nil {
+			t.Fatalf("store.Get(%s): %v", key, err)
+		}
+		if _, _, err := decodeUser(file, slotPassword, "test"); err == nil {
+			t.Errorf("%s opens with the old password", key)
+		}
+		if _, _, err := decodeUser(file, slotRecovery, normalizeRecoveryCode(code)); err == nil {
+			t.Errorf("%s opens with the used recovery code", key)
+		}
+	}
+	if snapshots < 2 {
+		t.Fatalf("snapshots: got: %d, want at least 2", snapshots)
+	}
+
+	// The snapshots still open with the User's data key.
+	snaps, err := m.Snapshots(usr)
+	if err != nil {
+		t.Fatalf("Snapshots: %v", err)
+	}
+	for _, s := range snaps {
+		if _, err := m.PreviewSnapshot(usr, s.ID); err != nil {
+			t.Errorf("PreviewSnapshot(%s): %v", s.ID, err)
+		}
+	}
+}
+
+func TestRestoreDeletedAttachment(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	m.SetSnapshots(10, 0)
+	usr, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	now := time.Now()
+	usr.ImportTransactions([]*register.Transaction{{ID: "trans", Date: &now}})
+	kept, err := m.AddAttachment(usr, "trans", "kept.png", "image/png", pngData)
+	if err != nil {
+		t.Fatalf("AddAttachment: %v", err)
+	}
+	deleted, err := m.AddAttachment(usr, "trans", "deleted.png", "image/png", pngData)
+	if err != nil {
+		t.Fatalf("AddAttachment: %v", err)
+	}
+	if err := m.saveUser(usr); err != nil {
+		t.Fatalf("saveUser: %v", err)
+	}
+	snaps, err := m.Snapshots(usr)
+	if err != nil {
+		t.Fatalf("Snapshots: %v", err)
+	}
+	if err := m.DeleteAttachment(usr, "trans", deleted.ID); err != nil {
+		t.Fatalf("DeleteAttachment: %v", err)
+	}
+	added, err := m.AddAttachment(usr, "trans", "added.png", "image/png", pngData)
+	if err != nil {
+		t.Fatalf("AddAttachment: %v", err)
+	}
+
+	if err := m.RestoreSnapshot(usr, snaps[0].ID); err != nil {
+		t.Fatalf("RestoreSnapshot: %v", err)
+	}
+	atts := usr.Transactions()[0].Attachments
+	if len(atts) != 1 || atts[0].ID != kept.ID {
+		t.Fatalf("attachments after restore: got: %v, want: [%s]", atts, kept.ID)
+	}
+	if _, _, err := m.Attachment(usr, "trans", kept.ID); err != nil {
+		t.Errorf("Attachment(%s): %v", kept.ID, err)
+	}
+	if _, _, err := m.store.Get(attachmentKey("test", added.ID)); err != ErrNoObject {
+		t.Errorf("an attachment added after the snapshot should be deleted: store.Get: %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const tmpPostfix = ".tmp*"
+
+var (
+	// ErrNoObject is returned when a VaultStore has nothing stored under a key.
+	ErrNoObject = errors.New("no such stored object")
+	// ErrVersionConflict is returned when a compare-and-swap finds a different version stored.
+	ErrVersionConflict = errors.New("stored object version conflict")
+)
+
+// VaultStore stores the encrypted save files, attachments and calendar feeds of users. Objects are
+// named by slash-separated keys, and each write gives an object a new opaque version, so that
+// writers can detect that someone else wrote an object since they read it.
+type VaultStore interface {
+	// Get returns the data stored under key and its version, or ErrNoObject.
+	Get(key string) ([]byte, string, error)
+	// Put stores data under key unconditionally and returns its new version.
+	Put(key string, data []byte) (string, error)
+	// CompareAndSwap stores data under key only if the stored version is still version, and
+	// returns its new version. An empty version means that nothing may be stored under key yet.
+	// ErrVersionConflict is returned otherwise.
+	CompareAndSwap(key, version string, data []byte) (string, error)
+	// Delete removes the object under key. Deleting a missing object is not an error.
+	Delete(key string) error
+	// List returns the sorted keys that start with prefix.
+	List(prefix string) ([]string, error)
+}
+
+// writeFile replaces file with data atomically. The data is written and synced to a temporary
+// file beside file, which is then renamed over it, so that a crash leaves either the old or the
+// new contents in place.
+func writeFile(file string, data []byte) error {
+	dir := filepath.Dir(file)
+	tmp, err := ioutil.TempFile(dir, filepath.Base(file)+tmpPostfix)
+	if err != nil {
+		return fmt.Errorf("ioutil.TempFile(%s): %v", dir, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Write(%s): %v", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Sync(%s): %v", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Close(%s): %v", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("os.Rename(%s): %v", tmp.Name(), err)
+	}
+	// Sync the directory so that the rename itself survives a crash.
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("os.Open(%s): %v", dir, err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("Sync(%s): %v", dir, err)
+	}
+	return nil
+}
+
+// NewFileStore returns a FileStore that keeps objects in files under dir.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// FileStore is a VaultStore backed by a directory. Each object is a file named by its key, and
+// replaced atomically when written. The version of an object is a hash of its contents.
+type FileStore struct {
+	// mu makes compare-and-swap atomic among the writers of this process.
+	mu  sync.Mutex
+	dir string
+}
+
+func (s *FileStore) file(key string) (string, error) {
+	if key == "" || path.IsAbs(key) || path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") {
+		return "", fmt.Errorf("invalid key: %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func fileVersion(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+func (s *FileStore) read(key string) ([]byte, string, error) {
+	file, err := s.file(key)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, "", ErrNoObject
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("ioutil.ReadFile(%s): %v", file, err)
+	}
+	return data, fileVersion(data), nil
+}
+
+func (s *FileStore) write(key string, data []byte) (string, error) {
+	file, err := s.file(key)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("os.MkdirAll(%s): %v", dir, err)
+	}
+	if err := writeFile(file, data); err != nil {
+		return "", fmt.Errorf("writeFile(%s): %v", file, err)
+	}
+	return fileVersion(data), nil
+}
+
+// Get returns the contents of the file for key.
+func (s *FileStore) Get(key string) ([]byte, string, error) {
+	return s.read(key)
+}
+
+// Put replaces the file for key with data.
+func (s *FileStore) Put(key string, data []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(key, data)
+}
+
+// CompareAndSwap replaces the file for key with data if its contents are still those of version.
+func (s *FileStore) CompareAndSwap(key, version string, data []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, stored, err := s.read(key)
+	if err != nil && err != ErrNoObject {
+		return "", err
+	}
+	if stored != version {
+		return "", ErrVersionConflict
+	}
+	return s.write(key, data)
+}
+
+// Delete removes the file for key.
+func (s *FileStore) Delete(key string) error {
+	file, err := s.file(key)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("os.Remove(%s): %v", file, err)
+	}
+	return nil
+}
+
+// List walks the directory that holds the keys with prefix. Temporary files of interrupted writes
+// are skipped.
+func (s *FileStore) List(prefix string) ([]string, error) {
+	root := s.dir
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir, err := s.file(prefix[:i])
+		if err != nil {
+			return nil, err
+		}
+		root = dir
+	}
+	var keys []string
+	err := filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if tmp, _ := filepath.Match("*"+tmpPostfix, info.Name()); tmp {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, file)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filepath.Walk(%s): %v", root, err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{objects: make(map[string]*memoryObject)}
+}
+
+// MemoryStore is a VaultStore that keeps objects in memory, for tests. The version of an object
+// counts the writes to the store.
+type MemoryStore struct {
+	mu      sync.Mutex
+	objects map[string]*memoryObject
+	writes  int
+}
+
+type memoryObject struct {
+	data    []byte
+	version string
+}
+
+func (s *MemoryStore) write(key string, data []byte) string {
+	s.writes++
+	obj := &memoryObject{
+		data:    append([]byte(nil), data...),
+		version: strconv.Itoa(s.writes),
+	}
+	s.objects[key] = obj
+	return obj.version
+}
+
+// Get returns a copy of the data stored under key.
+func (s *MemoryStore) Get(key string) ([]byte, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[key]
+	if !ok {
+		return nil, "", ErrNoObject
+	}
+	return append([]byte(nil), obj.data...), obj.version, nil
+}
+
+// Put stores a copy of data under key.
+func (s *MemoryStore) Put(key string, data []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(key, data), nil
+}
+
+// CompareAndSwap stores a copy of data under key if version is still stored.
+func (s *MemoryStore) CompareAndSwap(key, version string, data []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var stored string
+	if obj, ok := s.objects[key]; ok {
+		stored = obj.version
+	}
+	if stored != version {
+		return "", ErrVersionConflict
+	}
+	return s.write(key, data), nil
+}
+
+// Delete removes the object under key.
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+// List returns the keys stored with prefix.
+func (s *MemoryStore) List(prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,198 @@
+package session
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVaultStores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oyster-store")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	stores := []struct {
+		label string
+		store VaultStore
+	}{
+		{
+			label: "file",
+			store: NewFileStore(dir),
+		},
+		{
+			label: "memory",
+			store: NewMemoryStore(),
+		},
+	}
+	for _, test := range stores {
+		t.Run(test.label, func(t *testing.T) {
+			s := test.store
+			if _, _, err := s.Get("user"); err != ErrNoObject {
+				t.Errorf("Get missing: got: %v, want: %v", err, ErrNoObject)
+			}
+			if _, err := s.CompareAndSwap("user", "stale", []byte("v1")); err != ErrVersionConflict {
+				t.Errorf("CompareAndSwap missing with a version: got: %v, want: %v", err, ErrVersionConflict)
+			}
+			v1, err := s.CompareAndSwap("user", "", []byte("v1"))
+			if err != nil {
+				t.Fatalf("CompareAndSwap create: %v", err)
+			}
+			if _, err := s.CompareAndSwap("user", "", []byte("again")); err != ErrVersionConflict {
+				t.Errorf("CompareAndSwap create existing: got: %v, want: %v", err, ErrVersionConflict)
+			}
+			v2, err := s.CompareAndSwap("user", v1, []byte("v2"))
+			if err != nil {
+				t.Fatalf("CompareAndSwap: %v", err)
+			}
+			if _, err := s.CompareAndSwap("user", v1, []byte("lost")); err != ErrVersionConflict {
+				t.Errorf("CompareAndSwap stale: got: %v, want: %v", err, ErrVersionConflict)
+			}
+			data, version, err := s.Get("user")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if got, want := string(data), "v2"; got != want {
+				t.Errorf("Get: got: %s, want: %s", got, want)
+			}
+			if got, want := version, v2; got != want {
+				t.Errorf("version: got: %s, want: %s", got, want)
+			}
+
+			for _, key := range []string{"user-attachments/b", "user-attachments/a", "calendar-1"} {
+				if _, err := s.Put(key, []byte(key)); err != nil {
+					t.Fatalf("Put(%s): %v", key, err)
+				}
+			}
+			keys, err := s.List("user-attachments/")
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if got, want := keys, []string{"user-attachments/a", "user-attachments/b"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("List: got: %v, want: %v", got, want)
+			}
+			if keys, err := s.List("nothing/"); err != nil || len(keys) != 0 {
+				t.Errorf("List empty: got: %v, err: %v", keys, err)
+			}
+
+			if err := s.Delete("user-attachments/a"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if err := s.Delete("user-attachments/a"); err != nil {
+				t.Errorf("Delete missing: %v", err)
+			}
+			if _, _, err := s.Get("user-attachments/a"); err != ErrNoObject {
+				t.Errorf("Get deleted: got: %v, want: %v", err, ErrNoObject)
+			}
+		})
+	}
+}
+
+func TestFileStoreKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oyster-keys")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := NewFileStore(dir)
+	for _, key := range []string{"", "/etc/passwd", "../escape", "a/../../b", "a//b"} {
+		if _, err := s.Put(key, []byte("data")); err == nil {
+			t.Errorf("Put(%q): expected non-nil error", key)
+		}
+	}
+}
+
+func TestManagerFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oyster-manager")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := NewManager(NewFileStore(dir))
+	m.stop()
+	usr, token, _, err := m.Register("files", "files")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := usr.AddCategory("Groceries"); err != nil {
+		t.Fatalf("AddCategory: %v", err)
+	}
+	if err := m.Logout(token); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+
+	m = NewManager(NewFileStore(dir))
+	m.stop()
+	usr, _, _, err = m.Login("files", "files")
+	if err != nil || usr == nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !usr.HasCategory("Groceries") {
+		t.Error("the category should be saved")
+	}
+}
+
+func TestSaveUserConflict(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	usr, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	// Someone else replaces the save file while the User is live.
+	file, _, err := m.store.Get(userKey("test"))
+	if err != nil {
+		t.Fatalf("store.Get: %v", err)
+	}
+	if _, err := m.store.Put(userKey("test"), file); err != nil {
+		t.Fatalf("store.Put: %v", err)
+	}
+	if err := m.saveUser(usr); err == nil {
+		t.Fatal("saveUser over a newer save file: expected non-nil error")
+	}
+	if !usr.isDirty() {
+		t.Error("a failed save should leave the user dirty")
+	}
+	if stored, _, err := m.store.Get(userKey("test")); err != nil || !bytes.Equal(stored, file) {
+		t.Errorf("the newer save file should be kept: %v", err)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oyster-write")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "file")
+	for _, data := range []string{"old", "new"} {
+		if err := writeFile(file, []byte(data)); err != nil {
+			t.Fatalf("writeFile: %v", err)
+		}
+	}
+	got, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile: %v", err)
+	}
+	if want := "new"; string(got) != want {
+		t.Errorf("contents: got: %s, want: %s", got, want)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ioutil.ReadDir: %v", err)
+	}
+	if got, want := len(files), 1; got != want {
+		t.Errorf("files: got: %d, want: %d, temporary files should be renamed away", got, want)
+	}
+	if mode := files[0].Mode().Perm(); mode != 0600 {
+		t.Errorf("mode: got: %v, want: %v", mode, os.FileMode(0600))
+	}
+}
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"github.com/groggygopher/oyster/register"
+)
+
+func cleanTags(tags []string) ([]string, error) {
+	var cleaned []string
+	for _, tag := range tags {
+		c, err := register.CleanTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		cleaned = append(cleaned, c)
+	}
+	return cleaned, nil
+}
+
+// updateTags applies fn with each tag to every transaction with one of the given IDs and returns
+// the number of modified transactions. It must be called while holding u.mu.
+func (u *User) updateTags(ids, tags []string, fn func(*register.Transaction, string) bool) int {
+	want := make(map[string]bool)
+	for _, id := range ids {
+		want[id] = true
+	}
+	var count int
+	for _, t := range u.transactions {
+		if !want[t.ID] {
+			continue
+		}
+		changed := false
+		for _, tag := range tags {
+			changed = fn(t, tag) || changed
+		}
+		if changed {
+			count++
+		}
+	}
+	if count > 0 {
+		u.touch()
+	}
+	return count
+}
+
+// TagTransactions adds the given tags to every transaction with one of the given IDs, returning
+// the number of modified transactions.
+func (u *User) TagTransactions(ids, tags []string) (int, error) {
+	cleaned, err := cleanTags(tags)
+	if err != nil {
+		return 0, err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.updateTags(ids, cleaned, (*register.Transaction).AddTag), nil
+}
+
+// UntagTransactions removes the given tags from every transaction with one of the given IDs,
+// returning the number of modified transactions.
+func (u *User) UntagTransactions(ids, tags []string) (int, error) {
+	cleaned, err := cleanTags(tags)
+	if err != nil {
+		return 0, err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.updateTags(ids, cleaned, (*register.Transaction).RemoveTag), nil
+}
+
+// TaggedTransactions returns the transactions that have every one of the given tags.
+func (u *User) TaggedTransactions(tags []string) []*register.Transaction {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return register.FilterTags(u.transactions, tags)
+}
+
+// TagTotals returns the count and summed amount of every tag this User has applied.
+func (u *User) TagTotals() []*register.TagTotal {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return register.TagTotals(u.transactions)
+}
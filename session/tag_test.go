@@ -0,0 +1,60 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/groggygopher/oyster/register"
+	"github.com/groggygopher/oyster/rule"
+)
+
+func TestTagTransactions(t *testing.T) {
+	usr := &User{
+		Name: "test",
+		transactions: []*register.Transaction{
+			{ID: "a"},
+			{ID: "b"},
+			{ID: "c"},
+		},
+		manager:    rule.NewEmptyManager(),
+		categories: register.NewCategoryTree(),
+	}
+
+	if _, err := usr.TagTransactions([]string{"a"}, []string{" "}); err == nil {
+		t.Error("expected non-nil error for an empty tag")
+	}
+	n, err := usr.TagTransactions([]string{"a", "b", "unknown"}, []string{"vacation", " tax "})
+	if err != nil {
+		t.Fatalf("TagTransactions: %v", err)
+	}
+	if got, want := n, 2; got != want {
+		t.Errorf("tagged: got: %d, want: %d", got, want)
+	}
+	n, err = usr.UntagTransactions([]string{"b", "c"}, []string{"tax"})
+	if err != nil {
+		t.Fatalf("UntagTransactions: %v", err)
+	}
+	if got, want := n, 1; got != want {
+		t.Errorf("untagged: got: %d, want: %d", got, want)
+	}
+
+	// Tags must survive a round trip through serialization.
+	bs, err := usr.Serialize()
+	if err != nil {
+		t.Fatalf("user.Serialize: %v", err)
+	}
+	deser, err := DeserializeUser(bs)
+	if err != nil {
+		t.Fatalf("DeserializeUser: %v", err)
+	}
+	var got []string
+	for _, t := range deser.TaggedTransactions([]string{"vacation"}) {
+		got = append(got, t.ID)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tagged vacation: got: %v, want: %v", got, want)
+	}
+	if got, want := deser.transactions[0].Tags, []string{"tax", "vacation"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("deserialized tags: got: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+package session
+
+import (
+	"fmt"
+
+	"github.com/groggygopher/oyster/register"
+)
+
+// SuggestTransfers returns unlinked pairs of this User's transactions that look like transfers
+// between accounts, at most the given number of days apart.
+func (u *User) SuggestTransfers(days int) []*register.TransferPair {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return register.FindTransfers(u.transactions, days)
+}
+
+// Transfers returns every confirmed transfer between this User's accounts.
+func (u *User) Transfers() []*register.TransferPair {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return register.LinkedTransfers(u.transactions)
+}
+
+// LinkTransfer confirms that the two transactions with the given IDs are the halves of a single
+// transfer, which excludes them from spending reports.
+func (u *User) LinkTransfer(fromID, toID string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	from, err := u.transaction(fromID)
+	if err != nil {
+		return err
+	}
+	to, err := u.transaction(toID)
+	if err != nil {
+		return err
+	}
+	if err := register.LinkTransfer(from, to); err != nil {
+		return inputError(err)
+	}
+	u.touch()
+	return nil
+}
+
+// UnlinkTransfer breaks the transfer that the transaction with the given ID is a half of.
+func (u *User) UnlinkTransfer(id string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	t, err := u.transaction(id)
+	if err != nil {
+		return err
+	}
+	if !t.IsTransfer() {
+		return inputError(fmt.Errorf("transaction %s is not a linked transfer", id))
+	}
+	if other, err := u.transaction(t.TransferID); err == nil {
+		other.TransferID = ""
+	}
+	t.TransferID = ""
+	u.touch()
+	return nil
+}
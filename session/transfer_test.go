@@ -0,0 +1,32 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/groggygopher/oyster/register"
+)
+
+func TestLinkUnlinkTransfer(t *testing.T) {
+	out := &register.Transaction{ID: "out", Amount: -10}
+	in := &register.Transaction{ID: "in", Amount: 10}
+	usr := &User{transactions: []*register.Transaction{out, in}}
+
+	if err := usr.LinkTransfer("out", "missing"); err != ErrNoTransaction {
+		t.Errorf("unknown transaction: got: %v, want: %v", err, ErrNoTransaction)
+	}
+	if err := usr.LinkTransfer("out", "in"); err != nil {
+		t.Fatalf("LinkTransfer: %v", err)
+	}
+	if got, want := len(usr.Transfers()), 1; got != want {
+		t.Errorf("transfers: got: %d, want: %d", got, want)
+	}
+	if err := usr.UnlinkTransfer("in"); err != nil {
+		t.Fatalf("UnlinkTransfer: %v", err)
+	}
+	if out.IsTransfer() || in.IsTransfer() {
+		t.Error("both halves should be unlinked")
+	}
+	if err := usr.UnlinkTransfer("in"); err == nil {
+		t.Error("expected non-nil error unlinking a transaction that is not a transfer")
+	}
+}
@@ -3,41 +3,72 @@ package session
 import (
 	"bytes"
 	"compress/gzip"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"log"
 	"sort"
 	"sync"
-	"time"
 
+	"github.com/groggygopher/oyster/payee"
+	"github.com/groggygopher/oyster/recurring"
 	"github.com/groggygopher/oyster/register"
 	"github.com/groggygopher/oyster/rule"
 )
 
 type serializeableUser struct {
-	Name         string
-	Transactions []*register.Transaction
-	Rules        []*rule.Rule
+	// Version is the schema version of the payload; see userSchema.
+	Version       int
+	Name          string
+	Transactions  []*register.Transaction
+	Rules         []*rule.Rule
+	Categories    []string
+	Schedules     []*recurring.Schedule
+	CalendarToken string
+	Accounts      []*register.Account
+	Budgets       []*register.Budget
+	Payees        []*payee.Alias
+	Dismissed     []string
+	Merged        []string
+	Imports       []*ImportBatch
 }
 
-// DeserializeUser takes the given bytes and decodes a User.
+// DeserializeUser takes the given bytes and decodes a User, migrating payloads of older schema
+// versions to the current one.
 func DeserializeUser(b []byte) (*User, error) {
 	r := bytes.NewReader(b)
 	zr, err := gzip.NewReader(r)
 	if err != nil {
 		return nil, fmt.Errorf("gzip.NewReader: %v", err)
 	}
-	serUsr := &serializeableUser{}
+	doc := userDoc{}
 	jsonDec := json.NewDecoder(zr)
-	if err := jsonDec.Decode(serUsr); err != nil {
+	if err := jsonDec.Decode(&doc); err != nil {
 		return nil, fmt.Errorf("json.Decode: %v", err)
 	}
+	if err := doc.upgrade(); err != nil {
+		return nil, fmt.Errorf("upgrade: %v", err)
+	}
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal: %v", err)
+	}
+	serUsr := &serializeableUser{}
+	if err := json.Unmarshal(migrated, serUsr); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %v", err)
+	}
 
 	usr := &User{
-		Name:         serUsr.Name,
-		transactions: serUsr.Transactions,
-		manager:      rule.NewManager(serUsr.Rules),
+		Name:          serUsr.Name,
+		transactions:  serUsr.Transactions,
+		manager:       rule.NewManager(serUsr.Rules),
+		categories:    register.NewCategoryTree(serUsr.Categories...),
+		schedules:     serUsr.Schedules,
+		calendarToken: serUsr.CalendarToken,
+		accounts:      serUsr.Accounts,
+		budgets:       serUsr.Budgets,
+		payees:        payee.NewDirectory(serUsr.Payees),
+		dismissed:     serUsr.Dismissed,
+		merged:        serUsr.Merged,
+		imports:       serUsr.Imports,
 	}
 	return usr, nil
 }
@@ -45,13 +76,120 @@ func DeserializeUser(b []byte) (*User, error) {
 // User is contains all of a User's data.
 type User struct {
 	mu sync.Mutex
+	// saveMu serializes writes of the User's save file.
+	saveMu sync.Mutex
+	// dirty is set when the User changes, and cleared when they are saved. flush, if set, wakes
+	// the Manager's flusher after a change.
+	dirty bool
+	flush chan<- struct{}
+	// stored is the store version of the save file the User was last read from or written to.
+	stored string
 
 	Name string `json:"name"`
 
-	passkey []byte
-	// Most recent is at index len() - 1.
+	// passkey is the data key the User's vault and attachments are sealed under. It is wrapped
+	// by the password and recovery key slots.
+	passkey  []byte
+	password *keySlot
+	recovery *keySlot
+	// Most recent is at index 0.
 	transactions []*register.Transaction
 	manager      *rule.Manager
+	categories   *register.CategoryTree
+	schedules    []*recurring.Schedule
+	// calendarToken unlocks the User's calendar feed without a session.
+	calendarToken string
+	accounts      []*register.Account
+	budgets       []*register.Budget
+	payees        *payee.Directory
+	// Keys of pairs of transactions confirmed not to be duplicates, sorted.
+	dismissed []string
+	// IDs of duplicate transactions that were merged away.
+	merged  []string
+	imports []*ImportBatch
+	// ids indexes the IDs of every transaction and merged duplicate, so that imports only
+	// look up their own rows. It is rebuilt on first use after loading.
+	ids map[string]bool
+}
+
+// touch marks this User as changed since they were last saved and wakes the flusher. It must be
+// called while holding u.mu.
+func (u *User) touch() {
+	u.dirty = true
+	if u.flush != nil {
+		select {
+		case u.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// MarkDirty records a change to this User made other than through their own methods, such as to
+// their rules, so that the change is saved.
+func (u *User) MarkDirty() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.touch()
+}
+
+// isDirty returns true if this User has changed since they were last saved.
+func (u *User) isDirty() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.dirty
+}
+
+// setDirty marks this User as changed or saved.
+func (u *User) setDirty(dirty bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.dirty = dirty
+}
+
+// checkPassword returns an error unless password opens this User's password key slot.
+func (u *User) checkPassword(password string) error {
+	return u.checkSecret(slotPassword, password)
+}
+
+// checkSecret returns errWrongSecret unless secret opens this User's key slot of the given kind.
+func (u *User) checkSecret(kind byte, secret string) error {
+	u.mu.Lock()
+	slot, passkey := u.password, u.passkey
+	if kind == slotRecovery {
+		slot = u.recovery
+	}
+	u.mu.Unlock()
+	if slot == nil {
+		return errWrongSecret
+	}
+	key, err := slot.unwrap(secret)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(key, passkey) {
+		return errWrongSecret
+	}
+	return nil
+}
+
+// dataKey returns the key this User's vault and attachments are sealed under.
+func (u *User) dataKey() []byte {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.passkey
+}
+
+// keySlots returns the key slots of this User's vault, password first.
+func (u *User) keySlots() []*keySlot {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var slots []*keySlot
+	for _, s := range []*keySlot{u.password, u.recovery} {
+		if s != nil {
+			slots = append(slots, s)
+		}
+	}
+	return slots
 }
 
 // ImportTransactions imports new transactions from the given data, returning
@@ -59,23 +197,71 @@ type User struct {
 func (u *User) ImportTransactions(newTrans []*register.Transaction) int {
 	u.mu.Lock()
 	defer u.mu.Unlock()
+	fresh := u.newTransactions(newTrans, make(map[string]bool))
+	u.addTransactions(fresh)
+	if len(fresh) > 0 {
+		u.touch()
+	}
+	return len(fresh)
+}
 
-	has := make(map[string]*register.Transaction)
-	for _, t := range u.transactions {
-		has[t.ID] = t
+// index returns the set of IDs this User has ever imported, building it on first use. It must be
+// called while holding u.mu.
+func (u *User) index() map[string]bool {
+	if u.ids == nil {
+		u.ids = make(map[string]bool, len(u.transactions)+len(u.merged))
+		for _, t := range u.transactions {
+			u.ids[t.ID] = true
+		}
+		for _, id := range u.merged {
+			u.ids[id] = true
+		}
 	}
+	return u.ids
+}
 
-	var count int
+// newTransactions returns the given transactions that this User does not already have and that
+// are not in seen, with their payees set, and adds their IDs to seen. It must be called while
+// holding u.mu.
+func (u *User) newTransactions(newTrans []*register.Transaction, seen map[string]bool) []*register.Transaction {
+	ids := u.index()
+	var fresh []*register.Transaction
 	for _, t := range newTrans {
-		if _, ok := has[t.ID]; !ok {
-			u.transactions = append(u.transactions, t)
-			count++
+		if !ids[t.ID] && !seen[t.ID] {
+			seen[t.ID] = true
+			t.Payee = u.payees.Name(t.Description)
+			fresh = append(fresh, t)
 		}
 	}
-	sort.SliceStable(u.transactions, func(i, j int) bool {
-		return u.transactions[i].Date.After(*u.transactions[j].Date)
+	return fresh
+}
+
+func newer(a, b *register.Transaction) bool {
+	return a.Date != nil && (b.Date == nil || a.Date.After(*b.Date))
+}
+
+// addTransactions merges the given new transactions into the history, keeping the most recent
+// first, and indexes their IDs. Only the new transactions are sorted. It must be called while
+// holding u.mu.
+func (u *User) addTransactions(trans []*register.Transaction) {
+	if len(trans) == 0 {
+		return
+	}
+	sort.SliceStable(trans, func(i, j int) bool {
+		return newer(trans[i], trans[j])
 	})
-	return count
+	ids := u.index()
+	merged := make([]*register.Transaction, 0, len(u.transactions)+len(trans))
+	old := u.transactions
+	for _, t := range trans {
+		for len(old) > 0 && !newer(t, old[0]) {
+			merged = append(merged, old[0])
+			old = old[1:]
+		}
+		merged = append(merged, t)
+		ids[t.ID] = true
+	}
+	u.transactions = append(merged, old...)
 }
 
 // Transactions returns a slice of all transactions for this user.
@@ -96,9 +282,19 @@ func (u *User) Serialize() ([]byte, error) {
 	defer u.mu.Unlock()
 
 	serUsr := &serializeableUser{
-		Name:         u.Name,
-		Transactions: u.transactions,
-		Rules:        u.manager.Rules(),
+		Version:       userSchema,
+		Name:          u.Name,
+		Transactions:  u.transactions,
+		Rules:         u.manager.Rules(),
+		Categories:    u.categories.Names(),
+		Schedules:     u.schedules,
+		CalendarToken: u.calendarToken,
+		Accounts:      u.accounts,
+		Budgets:       u.budgets,
+		Payees:        u.payees.Aliases(),
+		Dismissed:     u.dismissed,
+		Merged:        u.merged,
+		Imports:       u.imports,
 	}
 	buf := &bytes.Buffer{}
 	zw := gzip.NewWriter(buf)
@@ -109,22 +305,3 @@ func (u *User) Serialize() ([]byte, error) {
 	zw.Close()
 	return buf.Bytes(), nil
 }
-
-// Session is a data model containing the user session state.
-type Session struct {
-	Start time.Time `json:"start"`
-	User  *User     `json:"user"`
-}
-
-// String creates a unique session token.
-func (s *Session) String() string {
-	buf := &bytes.Buffer{}
-	zw := gzip.NewWriter(buf)
-	jsonEnc := json.NewEncoder(zw)
-	if err := jsonEnc.Encode(s); err != nil {
-		log.Printf("error: json.Encode(session): %v", err)
-		return ""
-	}
-	zw.Close()
-	return base64.StdEncoding.EncodeToString(buf.Bytes())
-}
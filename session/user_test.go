@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/groggygopher/oyster/payee"
 	"github.com/groggygopher/oyster/register"
 	"github.com/groggygopher/oyster/rule"
 )
@@ -15,9 +16,12 @@ func TestSerializeDeserialize(t *testing.T) {
 		transactions: []*register.Transaction{
 			&register.Transaction{
 				Description: "test",
+				Payee:       "Test",
 			},
 		},
-		manager: rule.NewManager([]*rule.Rule{&rule.Rule{Name: "test"}}),
+		manager:    rule.NewManager([]*rule.Rule{&rule.Rule{Name: "test"}}),
+		categories: register.NewCategoryTree("test"),
+		payees:     payee.NewDirectory(nil),
 	}
 
 	bs, err := usr.Serialize()
@@ -193,3 +197,30 @@ func TestTransactions(t *testing.T) {
 		})
 	}
 }
+
+func TestImportTransactionsOrder(t *testing.T) {
+	day := func(d int) *time.Time {
+		t := time.Date(2018, time.May, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	usr := &User{}
+	usr.ImportTransactions([]*register.Transaction{
+		{ID: "3", Date: day(3)},
+		{ID: "1", Date: day(1)},
+	})
+	usr.ImportTransactions([]*register.Transaction{
+		{ID: "2", Date: day(2)},
+		{ID: "4", Date: day(4)},
+		{ID: "1", Date: day(1)},
+		{ID: "0", Date: day(0)},
+		{ID: "0", Date: day(0)},
+	})
+
+	var got []string
+	for _, t := range usr.Transactions() {
+		got = append(got, t.ID)
+	}
+	if want := []string{"4", "3", "2", "1", "0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,563 @@
+package session
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Save files, or vaults, start with a header that describes how they are encrypted:
+//
+//	magic    "OYSTER"
+//	version  1 byte
+//	cipher   1 byte id
+//	slots    1 byte count, then for each key slot:
+//	  kind     1 byte
+//	  kdf      1 byte id, 1 byte length, then the parameters of the kdf
+//	  salt     1 byte length, then the salt
+//	  wrapped  1 byte length, then the wrapped data key
+//
+// followed by the User sealed under a random data key. Attachments are sealed under the same key,
+// with their store key as additional data so that one cannot be swapped for another. Each key slot
+// wraps the data key under a key derived from a secret, either the password or the recovery code,
+// so that either can open the vault. The header is authenticated as the additional data of the
+// cipher, so that it cannot be tampered with, for example to weaken the kdf.
+//
+// Legacy files have no header at all, and seal the User directly under an unsalted password key.
+const (
+	vaultMagic = "OYSTER"
+	// vaultVersion is the version of the files this server writes.
+	vaultVersion = 1
+
+	kdfScrypt    = 1
+	cipherAESGCM = 1
+
+	// The kinds of key slot.
+	slotPassword = 1
+	slotRecovery = 2
+
+	// scrypt cost parameters for new keys: N = 1<<scryptLogN.
+	scryptLogN = 15
+	scryptR    = 8
+	scryptP    = 1
+	saltLen    = 16
+	// The most costly scrypt parameters a save file may ask for, so that a tampered header cannot
+	// make a login allocate gigabytes or run for hours before its password is checked.
+	maxScryptLogN   = 22
+	maxScryptR      = 32
+	maxScryptP      = 16
+	maxScryptMemory = 1 << 30
+
+	// recoveryLen is the number of random bytes in a recovery code.
+	recoveryLen = 20
+
+	// rekeyPostfix marks an attachment that was re-encrypted under a new key but not yet moved
+	// over the original.
+	rekeyPostfix = ".rekey"
+)
+
+// kdfParams are the salt and cost of the scrypt key derivation of a save file.
+type kdfParams struct {
+	LogN, R, P uint8
+	Salt       []byte
+}
+
+// newKDFParams returns the current default parameters with a fresh random salt.
+func newKDFParams() (*kdfParams, error) {
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("io.ReadFull(random): %v", err)
+	}
+	return &kdfParams{LogN: scryptLogN, R: scryptR, P: scryptP, Salt: salt}, nil
+}
+
+// key derives the AES key for the given password.
+func (k *kdfParams) key(password string) ([]byte, error) {
+	key, err := scrypt.Key([]byte(password), k.Salt, 1<<k.LogN, int(k.R), int(k.P), aesKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("scrypt.Key: %v", err)
+	}
+	return key, nil
+}
+
+// validate returns an error if these parameters are not usable, or too costly to derive a key with.
+func (k *kdfParams) validate() error {
+	if k.LogN == 0 || k.LogN > maxScryptLogN {
+		return fmt.Errorf("scrypt log N must be from 1 to %d, got %d", maxScryptLogN, k.LogN)
+	}
+	if k.R == 0 || k.R > maxScryptR {
+		return fmt.Errorf("scrypt r must be from 1 to %d, got %d", maxScryptR, k.R)
+	}
+	if k.P == 0 || k.P > maxScryptP {
+		return fmt.Errorf("scrypt p must be from 1 to %d, got %d", maxScryptP, k.P)
+	}
+	if mem := 128 * int64(k.R) << k.LogN; mem > maxScryptMemory {
+		return fmt.Errorf("scrypt needs %d bytes of memory, more than the limit of %d", mem, maxScryptMemory)
+	}
+	return nil
+}
+
+// weak returns true if these parameters are cheaper than the current defaults.
+func (k *kdfParams) weak() bool {
+	return k.LogN < scryptLogN || k.R < scryptR || k.P < scryptP
+}
+
+// keySlot is the data key of a vault wrapped under a key derived from a secret.
+type keySlot struct {
+	kind    byte
+	kdf     *kdfParams
+	wrapped []byte
+}
+
+// newKeySlot wraps dataKey under the key derived from secret with kdf.
+func newKeySlot(kind byte, kdf *kdfParams, secret string, dataKey []byte) (*keySlot, error) {
+	key, err := kdf.key(secret)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := seal(key, dataKey, []byte{kind})
+	if err != nil {
+		return nil, fmt.Errorf("seal: %v", err)
+	}
+	return &keySlot{kind: kind, kdf: kdf, wrapped: wrapped}, nil
+}
+
+// unwrap returns the data key wrapped by this slot if secret opens it, and errWrongSecret
+// otherwise.
+func (s *keySlot) unwrap(secret string) ([]byte, error) {
+	key, err := s.kdf.key(secret)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := unseal(key, s.wrapped, []byte{s.kind})
+	if err != nil {
+		return nil, errWrongSecret
+	}
+	return dataKey, nil
+}
+
+// newDataKey returns a random key to seal a vault with.
+func newDataKey() ([]byte, error) {
+	key := make([]byte, aesKeyLen)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("io.ReadFull(random): %v", err)
+	}
+	return key, nil
+}
+
+// errWrongSecret is returned when a password or recovery code does not open a User's vault.
+var errWrongSecret = errors.New("wrong password or recovery code")
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCode returns a random recovery code, in groups of four characters for reading aloud
+// or writing down.
+func newRecoveryCode() (string, error) {
+	raw := make([]byte, recoveryLen)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return "", fmt.Errorf("io.ReadFull(random): %v", err)
+	}
+	enc := recoveryEncoding.EncodeToString(raw)
+	var groups []string
+	for len(enc) > 4 {
+		groups = append(groups, enc[:4])
+		enc = enc[4:]
+	}
+	return strings.Join(append(groups, enc), "-"), nil
+}
+
+// normalizeRecoveryCode strips the grouping and case from a recovery code as typed by a user.
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, code)
+}
+
+// vaultHeader returns the current vault header for the given key slots.
+func vaultHeader(slots ...*keySlot) []byte {
+	h := append([]byte(vaultMagic), vaultVersion, cipherAESGCM, byte(len(slots)))
+	for _, s := range slots {
+		k := s.kdf
+		h = append(h, s.kind, kdfScrypt, 3, k.LogN, k.R, k.P, byte(len(k.Salt)))
+		h = append(h, k.Salt...)
+		h = append(h, byte(len(s.wrapped)))
+		h = append(h, s.wrapped...)
+	}
+	return h
+}
+
+// vaultInfo is what a vault header says about a save file. aad is the header itself, which is
+// authenticated. Legacy files have a zero version and no key slots.
+type vaultInfo struct {
+	version int
+	slots   []*keySlot
+	aad     []byte
+}
+
+// headerReader reads the fields of a vault header.
+type headerReader struct {
+	b   []byte
+	err error
+}
+
+func (r *headerReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.b) < n {
+		r.err = errors.New("vault header is truncated")
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *headerReader) byte() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+// kdf reads the id, parameters and salt of a key derivation.
+func (r *headerReader) kdf() (*kdfParams, error) {
+	id := r.byte()
+	params := r.bytes(int(r.byte()))
+	salt := r.bytes(int(r.byte()))
+	if r.err != nil {
+		return nil, r.err
+	}
+	if id != kdfScrypt {
+		return nil, fmt.Errorf("unsupported vault key derivation: %d", id)
+	}
+	if len(params) != 3 {
+		return nil, fmt.Errorf("scrypt needs 3 parameters, got %d", len(params))
+	}
+	k := &kdfParams{LogN: params[0], R: params[1], P: params[2], Salt: salt}
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// cipher reads the id of the cipher the vault is sealed with.
+func (r *headerReader) cipher() error {
+	if id := r.byte(); r.err == nil && id != cipherAESGCM {
+		return fmt.Errorf("unsupported vault cipher: %d", id)
+	}
+	return r.err
+}
+
+// parseVault splits a save file into what its header says and its sealed body.
+func parseVault(file []byte) (*vaultInfo, []byte, error) {
+	if !bytes.HasPrefix(file, []byte(vaultMagic)) {
+		return &vaultInfo{}, file, nil
+	}
+	r := &headerReader{b: file[len(vaultMagic):]}
+	info := &vaultInfo{version: int(r.byte())}
+	if r.err == nil && info.version != vaultVersion {
+		return nil, nil, fmt.Errorf("unsupported vault version %d: this server reads version %d", info.version, vaultVersion)
+	}
+	if err := r.cipher(); err != nil {
+		return nil, nil, err
+	}
+	n := int(r.byte())
+	for i := 0; i < n && r.err == nil; i++ {
+		slot := &keySlot{kind: r.byte()}
+		var err error
+		if slot.kdf, err = r.kdf(); err != nil {
+			return nil, nil, err
+		}
+		slot.wrapped = r.bytes(int(r.byte()))
+		info.slots = append(info.slots, slot)
+	}
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+	info.aad = file[:len(file)-len(r.b)]
+	return info, r.b, nil
+}
+
+// newPasswordSlot wraps dataKey under a key derived from password with a fresh salt.
+func newPasswordSlot(password string, dataKey []byte) (*keySlot, error) {
+	kdf, err := newKDFParams()
+	if err != nil {
+		return nil, err
+	}
+	return newKeySlot(slotPassword, kdf, password, dataKey)
+}
+
+// newRecoverySlot wraps dataKey under a new recovery code, and returns the code with the slot.
+func newRecoverySlot(dataKey []byte) (string, *keySlot, error) {
+	code, err := newRecoveryCode()
+	if err != nil {
+		return "", nil, err
+	}
+	kdf, err := newKDFParams()
+	if err != nil {
+		return "", nil, err
+	}
+	slot, err := newKeySlot(slotRecovery, kdf, normalizeRecoveryCode(code), dataKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return code, slot, nil
+}
+
+// setPassword wraps the given User's data key under a key derived from password with a fresh
+// salt, replacing their previous password.
+func setPassword(usr *User, password string) error {
+	slot, err := newPasswordSlot(password, usr.dataKey())
+	if err != nil {
+		return err
+	}
+	usr.mu.Lock()
+	defer usr.mu.Unlock()
+	usr.password = slot
+	return nil
+}
+
+// setRecoveryCode wraps the given User's data key under a new recovery code, replacing their
+// previous one, and returns the code.
+func setRecoveryCode(usr *User) (string, error) {
+	code, slot, err := newRecoverySlot(usr.dataKey())
+	if err != nil {
+		return "", err
+	}
+	usr.mu.Lock()
+	defer usr.mu.Unlock()
+	usr.recovery = slot
+	return code, nil
+}
+
+// rekey moves the given User to a new random data key, re-encrypts their attachments under it and
+// saves them, with a password slot for password. It upgrades legacy files, whose data was sealed
+// under the password key itself. Those files had no recovery code, so a new one is set and
+// returned, and must be shown to the User now.
+func (m *Manager) rekey(usr *User, password string) (string, error) {
+	dataKey, err := newDataKey()
+	if err != nil {
+		return "", err
+	}
+	slot, err := newPasswordSlot(password, dataKey)
+	if err != nil {
+		return "", err
+	}
+	code, recovery, err := newRecoverySlot(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	// Write every attachment under the new key beside the original, save the User, and only then
+	// replace the originals. finishRekey completes an interrupted rekey.
+	if err := m.resealAttachments(usr, dataKey); err != nil {
+		return "", err
+	}
+
+	usr.mu.Lock()
+	oldKey, oldPassword, oldRecovery := usr.passkey, usr.password, usr.recovery
+	usr.passkey, usr.password, usr.recovery = dataKey, slot, recovery
+	usr.mu.Unlock()
+	if err := m.saveUser(usr); err != nil {
+		usr.mu.Lock()
+		usr.passkey, usr.password, usr.recovery = oldKey, oldPassword, oldRecovery
+		usr.mu.Unlock()
+		return "", fmt.Errorf("saveUser: %v", err)
+	}
+	if err := m.finishRekey(usr); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// resealAttachments writes every attachment of the given User beside the original, sealed under
+// dataKey and bound to its name instead of under their legacy key without additional data.
+// finishRekey moves them over the originals once the User is saved.
+func (m *Manager) resealAttachments(usr *User, dataKey []byte) error {
+	var ids []string
+	usr.mu.Lock()
+	for _, t := range usr.transactions {
+		for _, a := range t.Attachments {
+			ids = append(ids, a.ID)
+		}
+	}
+	passkey := usr.passkey
+	usr.mu.Unlock()
+	for _, id := range ids {
+		key := attachmentKey(usr.Name, id)
+		encrypted, _, err := m.store.Get(key)
+		if err == ErrNoObject {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("store.Get(%s): %v", key, err)
+		}
+		data, err := unseal(passkey, encrypted, nil)
+		if err != nil {
+			return fmt.Errorf("unseal(%s): %v", id, err)
+		}
+		if encrypted, err = seal(dataKey, data, []byte(key)); err != nil {
+			return fmt.Errorf("seal(%s): %v", id, err)
+		}
+		if _, err := m.store.Put(key+rekeyPostfix, encrypted); err != nil {
+			return fmt.Errorf("store.Put(%s): %v", key+rekeyPostfix, err)
+		}
+	}
+	return nil
+}
+
+// finishRekey moves attachments re-encrypted by an interrupted rekey over their originals if they
+// open under the User's current key, and discards them otherwise.
+func (m *Manager) finishRekey(usr *User) error {
+	prefix := attachmentPrefix(usr.Name)
+	keys, err := m.store.List(prefix)
+	if err != nil {
+		return fmt.Errorf("store.List(%s): %v", prefix, err)
+	}
+	for _, key := range keys {
+		if !strings.HasSuffix(key, rekeyPostfix) {
+			continue
+		}
+		encrypted, _, err := m.store.Get(key)
+		if err != nil {
+			return fmt.Errorf("store.Get(%s): %v", key, err)
+		}
+		original := strings.TrimSuffix(key, rekeyPostfix)
+		if _, err := unseal(usr.dataKey(), encrypted, []byte(original)); err == nil {
+			if _, err := m.store.Put(original, encrypted); err != nil {
+				return fmt.Errorf("store.Put(%s): %v", original, err)
+			}
+		}
+		if err := m.store.Delete(key); err != nil {
+			return fmt.Errorf("store.Delete(%s): %v", key, err)
+		}
+	}
+	return nil
+}
+
+// openUser returns the named User once secret opens their key slot of the given kind: the live
+// User if they have a session, so that their unsaved changes are kept, or else the User in their
+// save file. errWrongSecret is returned if the secret does not open the slot, and ErrNoObject if
+// they have no save file.
+func (m *Manager) openUser(name string, kind byte, secret string) (*User, error) {
+	m.mu.Lock()
+	live, ok := m.users[name]
+	m.mu.Unlock()
+	if ok {
+		if err := live.user.checkSecret(kind, secret); err != nil {
+			return nil, err
+		}
+		return live.user, nil
+	}
+	usr, _, err := m.readUser(name, kind, secret)
+	return usr, err
+}
+
+// ChangePassword wraps the data key of the named User under a key derived from newPassword, once
+// oldPassword is verified against their save file. The vault and attachments stay sealed under the
+// same data key, so only the header of the save file changes. Every session of the User is ended,
+// and a new session is returned in their place. Nil is returned if oldPassword is wrong.
+func (m *Manager) ChangePassword(name, oldPassword, newPassword string) (*User, string, error) {
+	if len(newPassword) < 4 {
+		return nil, "", errors.New("Password must be at least 4 characters long")
+	}
+	usr, err := m.openUser(name, slotPassword, oldPassword)
+	if err == errWrongSecret || err == ErrNoObject {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("openUser(%s): %v", name, err)
+	}
+
+	// Derive the new key slot before taking m.mu, which every login waits on.
+	slot, err := newPasswordSlot(newPassword, usr.dataKey())
+	if err != nil {
+		return nil, "", fmt.Errorf("newPasswordSlot(%s): %v", name, err)
+	}
+	return m.replaceSlots(usr, slot, nil)
+}
+
+// NewRecoveryCode replaces the recovery code of the given User, saves them and returns the new
+// code. The code is not stored anywhere in the clear, so it must be shown to the User now.
+func (m *Manager) NewRecoveryCode(usr *User) (string, error) {
+	code, err := setRecoveryCode(usr)
+	if err != nil {
+		return "", fmt.Errorf("setRecoveryCode(%s): %v", usr.Name, err)
+	}
+	if err := m.saveUser(usr); err != nil {
+		return "", fmt.Errorf("saveUser(%s): %v", usr.Name, err)
+	}
+	return code, nil
+}
+
+// Recover sets a new password for the named User, who has forgotten theirs, given their recovery
+// code. Recovery codes are single use, so a new one is returned along with the User and a new
+// session in place of all of their others. Nil is returned if the code is wrong.
+func (m *Manager) Recover(name, code, newPassword string) (*User, string, string, error) {
+	if len(newPassword) < 4 {
+		return nil, "", "", errors.New("Password must be at least 4 characters long")
+	}
+	usr, err := m.openUser(name, slotRecovery, normalizeRecoveryCode(code))
+	if err == errWrongSecret || err == ErrNoObject {
+		return nil, "", "", nil
+	}
+	if err != nil {
+		return nil, "", "", fmt.Errorf("openUser(%s): %v", name, err)
+	}
+
+	slot, err := newPasswordSlot(newPassword, usr.dataKey())
+	if err != nil {
+		return nil, "", "", fmt.Errorf("newPasswordSlot(%s): %v", name, err)
+	}
+	newCode, recovery, err := newRecoverySlot(usr.dataKey())
+	if err != nil {
+		return nil, "", "", fmt.Errorf("newRecoverySlot(%s): %v", name, err)
+	}
+	usr, enc, err := m.replaceSlots(usr, slot, recovery)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return usr, enc, newCode, nil
+}
+
+// replaceSlots gives the given User the new password slot, and the new recovery slot unless it is
+// nil, ends every one of their sessions and saves them, returning the User and a new session. The
+// live User takes the slots if another login loaded them since usr was read. The User stays live
+// with the new slots if the save fails, and the flusher saves them later.
+func (m *Manager) replaceSlots(usr *User, password, recovery *keySlot) (*User, string, error) {
+	m.mu.Lock()
+	if live, ok := m.users[usr.Name]; ok && live.user != usr {
+		if !bytes.Equal(live.user.dataKey(), usr.dataKey()) {
+			m.mu.Unlock()
+			return nil, "", fmt.Errorf("the data key of %s changed while it was read", usr.Name)
+		}
+		usr = live.user
+	}
+	usr.mu.Lock()
+	usr.password = password
+	if recovery != nil {
+		usr.recovery = recovery
+	}
+	usr.touch()
+	usr.mu.Unlock()
+	enc, err := m.restartSessions(usr)
+	m.mu.Unlock()
+	if err != nil {
+		return nil, "", fmt.Errorf("restartSessions(%s): %v", usr.Name, err)
+	}
+	if err := m.saveUser(usr); err != nil {
+		return nil, "", fmt.Errorf("saveUser(%s): %v", usr.Name, err)
+	}
+	return usr, enc, nil
+}
@@ -0,0 +1,358 @@
+package session
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/groggygopher/oyster/payee"
+	"github.com/groggygopher/oyster/register"
+	"github.com/groggygopher/oyster/rule"
+)
+
+func TestLoginUpgradesLegacyFile(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+
+	// Write a save file and an attachment the way they were before key derivation headers.
+	legacyKey := generatePasskey("legacy")
+	usr := &User{
+		Name:    "legacy",
+		manager: rule.NewEmptyManager(),
+		payees:  payee.NewDirectory(nil),
+		transactions: []*register.Transaction{
+			{ID: "trans", Attachments: []*register.Attachment{{ID: "ATT-1"}}},
+		},
+	}
+	plain, err := usr.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+	sealed, err := seal(legacyKey, plain, nil)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if _, err := m.store.Put(userKey("legacy"), sealed); err != nil {
+		t.Fatalf("store.Put: %v", err)
+	}
+	sealed, err = seal(legacyKey, pngData, nil)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if _, err := m.store.Put(attachmentKey("legacy", "ATT-1"), sealed); err != nil {
+		t.Fatalf("store.Put: %v", err)
+	}
+
+	if _, _, _, err := m.Login("legacy", "wrong"); err == nil {
+		t.Error("Login with wrong password: expected non-nil error")
+	}
+	loggedIn, _, code, err := m.Login("legacy", "legacy")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if loggedIn.password == nil || bytes.Equal(loggedIn.passkey, legacyKey) {
+		t.Fatal("Login did not move the user to a data key")
+	}
+	if code == "" {
+		t.Error("Login should return the recovery code set by the upgrade")
+	}
+
+	file, _, err := m.store.Get(userKey("legacy"))
+	if err != nil {
+		t.Fatalf("store.Get: %v", err)
+	}
+	hdr, _, err := parseVault(file)
+	if err != nil {
+		t.Fatalf("parseVault: %v", err)
+	}
+	if got, want := hdr.version, vaultVersion; got != want {
+		t.Errorf("save file version: got: %d, want: %d", got, want)
+	}
+	if got, want := len(hdr.slots), 2; got != want {
+		t.Fatalf("key slots: got: %d, want: %d", got, want)
+	}
+	if k := hdr.slots[0].kdf; k.weak() {
+		t.Errorf("save file header: got: %+v, want current parameters", k)
+	}
+	if _, data, err := m.Attachment(loggedIn, "trans", "ATT-1"); err != nil || !bytes.Equal(data, pngData) {
+		t.Errorf("Attachment after upgrade: err: %v", err)
+	}
+
+	// Logging in again works with the upgraded keys, and sets no new code.
+	if _, _, again, err := m.Login("legacy", "legacy"); err != nil || again != "" {
+		t.Errorf("Login after upgrade: code: %q, err: %v", again, err)
+	}
+	if recovered, _, _, err := m.Recover("legacy", code, "changed"); err != nil || recovered == nil {
+		t.Errorf("Recover with the upgrade's code: got: %v, %v", recovered, err)
+	}
+}
+
+func TestFinishRekey(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	usr, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	good, err := seal(usr.passkey, pngData, []byte(attachmentKey("test", "ATT-good")))
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	stale, err := seal([]byte("otherotherotherotherotherotherot"), pngData, nil)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	goodKey := attachmentKey("test", "ATT-good")
+	staleKey := attachmentKey("test", "ATT-stale")
+	for key, data := range map[string][]byte{goodKey + rekeyPostfix: good, staleKey + rekeyPostfix: stale, staleKey: stale} {
+		if _, err := m.store.Put(key, data); err != nil {
+			t.Fatalf("store.Put: %v", err)
+		}
+	}
+
+	if err := m.finishRekey(usr); err != nil {
+		t.Fatalf("finishRekey: %v", err)
+	}
+	if data, _, err := m.store.Get(goodKey); err != nil || !bytes.Equal(data, good) {
+		t.Errorf("re-encrypted attachment should replace the original: %v", err)
+	}
+	if _, _, err := m.store.Get(goodKey + rekeyPostfix); err != ErrNoObject {
+		t.Errorf("re-encrypted attachment should be moved: %v", err)
+	}
+	if _, _, err := m.store.Get(staleKey + rekeyPostfix); err != ErrNoObject {
+		t.Errorf("stale attachment should be removed: %v", err)
+	}
+	if data, _, err := m.store.Get(staleKey); err != nil || !bytes.Equal(data, stale) {
+		t.Errorf("original attachment should be kept: %v", err)
+	}
+}
+
+func TestParseVault(t *testing.T) {
+	k := &kdfParams{LogN: 1, R: 8, P: 1, Salt: []byte("saltsaltsaltsalt")}
+	current := vaultHeader(&keySlot{kind: slotPassword, kdf: k, wrapped: []byte("wrapped")})
+	tests := []struct {
+		label   string
+		file    []byte
+		version int
+		wantErr bool
+	}{
+		{
+			label: "legacy",
+			file:  []byte("sealed"),
+		},
+		{
+			label:   "current",
+			file:    append(append([]byte{}, current...), "sealed"...),
+			version: vaultVersion,
+		},
+		{
+			label:   "unknown version",
+			file:    append([]byte(vaultMagic), vaultVersion+1, 0),
+			wantErr: true,
+		},
+		{
+			label:   "unknown kdf",
+			file:    append([]byte(vaultMagic), vaultVersion, cipherAESGCM, 1, slotPassword, 9, 3, 1, 8, 1, 0, 0),
+			wantErr: true,
+		},
+		{
+			label:   "costly log N",
+			file:    append([]byte(vaultMagic), vaultVersion, cipherAESGCM, 1, slotPassword, kdfScrypt, 3, 40, 8, 1, 0, 0),
+			wantErr: true,
+		},
+		{
+			label:   "zero r",
+			file:    append([]byte(vaultMagic), vaultVersion, cipherAESGCM, 1, slotPassword, kdfScrypt, 3, 15, 0, 1, 0, 0),
+			wantErr: true,
+		},
+		{
+			label:   "costly p",
+			file:    append([]byte(vaultMagic), vaultVersion, cipherAESGCM, 1, slotPassword, kdfScrypt, 3, 15, 8, 255, 0, 0),
+			wantErr: true,
+		},
+		{
+			label:   "costly memory",
+			file:    append([]byte(vaultMagic), vaultVersion, cipherAESGCM, 1, slotPassword, kdfScrypt, 3, 22, 32, 1, 0, 0),
+			wantErr: true,
+		},
+		{
+			label:   "unknown cipher",
+			file:    append([]byte(vaultMagic), vaultVersion, 9, 0),
+			wantErr: true,
+		},
+		{
+			label:   "truncated",
+			file:    current[:len(current)-4],
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			hdr, _, err := parseVault(test.file)
+			if got, want := err != nil, test.wantErr; got != want {
+				t.Fatalf("error: got: %t, want: %t, err: %v", got, want, err)
+			}
+			if err != nil {
+				return
+			}
+			if got, want := hdr.version, test.version; got != want {
+				t.Errorf("version: got: %d, want: %d", got, want)
+			}
+		})
+	}
+}
+
+func TestVaultHeaderAuthenticated(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	file, _, err := m.store.Get(userKey("test"))
+	if err != nil {
+		t.Fatalf("store.Get: %v", err)
+	}
+	// Weaken the scrypt cost in the header; the body must no longer decrypt.
+	file[len(vaultMagic)+7]--
+	if _, _, err := decodeUser(file, slotPassword, "test"); err == nil {
+		t.Error("decodeUser of a tampered header should fail")
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	_, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	// Both sessions share the live User.
+	usr, other, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	now := time.Now()
+	usr.ImportTransactions([]*register.Transaction{{ID: "trans", Date: &now}})
+	if _, err := m.AddAttachment(usr, "trans", "receipt.png", "image/png", pngData); err != nil {
+		t.Fatalf("AddAttachment: %v", err)
+	}
+
+	if changed, _, err := m.ChangePassword("test", "wrong", "changed"); err != nil || changed != nil {
+		t.Fatalf("ChangePassword with the wrong password: got: %v, %v, want: nil, nil", changed, err)
+	}
+	changed, enc, err := m.ChangePassword("test", "test", "changed")
+	if err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	if changed != usr {
+		t.Error("ChangePassword should keep the live User")
+	}
+	for _, tok := range []string{token, other} {
+		if m.ValidSession(tok) != nil {
+			t.Errorf("session %s should have ended", tok)
+		}
+	}
+	if m.ValidSession(enc) != usr {
+		t.Error("ChangePassword should start a new session")
+	}
+
+	if _, _, _, err := m.Login("test", "test"); err == nil {
+		t.Error("Login with the old password should fail")
+	}
+	loggedIn, _, _, err := m.Login("test", "changed")
+	if err != nil || loggedIn == nil {
+		t.Fatalf("Login with the new password: %v", err)
+	}
+	atts := loggedIn.Transactions()[0].Attachments
+	if len(atts) != 1 {
+		t.Fatalf("attachments: got: %d, want: 1", len(atts))
+	}
+	if _, data, err := m.Attachment(loggedIn, "trans", atts[0].ID); err != nil || !bytes.Equal(data, pngData) {
+		t.Errorf("Attachment after password change: err: %v", err)
+	}
+}
+
+func TestChangePasswordChecks(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	usr, _, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	// The live User's password is checked, even before it is saved.
+	if err := setPassword(usr, "unsaved"); err != nil {
+		t.Fatalf("setPassword: %v", err)
+	}
+	if changed, _, err := m.ChangePassword("test", "test", "changed"); err != nil || changed != nil {
+		t.Errorf("ChangePassword with the saved password: got: %v, %v, want: nil, nil", changed, err)
+	}
+	if changed, _, err := m.ChangePassword("test", "unsaved", "changed"); err != nil || changed != usr {
+		t.Errorf("ChangePassword with the live password: got: %v, %v, want: live User", changed, err)
+	}
+
+	// A save file that cannot be read is an error rather than a wrong password.
+	if _, err := m.store.Put(userKey("broken"), []byte(vaultMagic+"\x09")); err != nil {
+		t.Fatalf("store.Put: %v", err)
+	}
+	if _, _, err := m.ChangePassword("broken", "test", "changed"); err == nil {
+		t.Error("ChangePassword of an unreadable save file: expected non-nil error")
+	}
+	if _, _, _, err := m.Recover("broken", "AAAA-BBBB", "changed"); err == nil {
+		t.Error("Recover of an unreadable save file: expected non-nil error")
+	}
+	if changed, _, err := m.ChangePassword("nobody", "test", "changed"); err != nil || changed != nil {
+		t.Errorf("ChangePassword of an unknown User: got: %v, %v, want: nil, nil", changed, err)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	m, err := CreateTestManager()
+	if err != nil {
+		t.Fatalf("CreateTestManager: %v", err)
+	}
+	usr, token, _, err := m.Login("test", "test")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	code, err := m.NewRecoveryCode(usr)
+	if err != nil {
+		t.Fatalf("NewRecoveryCode: %v", err)
+	}
+
+	if recovered, _, _, err := m.Recover("test", "AAAA-BBBB", "changed"); err != nil || recovered != nil {
+		t.Fatalf("Recover with the wrong code: got: %v, %v, want: nil, nil", recovered, err)
+	}
+	// Codes are accepted without their grouping and in any case.
+	typed := strings.ToLower(strings.Replace(code, "-", " ", -1))
+	recovered, enc, next, err := m.Recover("test", typed, "changed")
+	if err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	if recovered != usr {
+		t.Error("Recover should keep the live User")
+	}
+	if m.ValidSession(token) != nil || m.ValidSession(enc) != usr {
+		t.Error("Recover should replace the User's sessions")
+	}
+	if next == code {
+		t.Error("Recover should issue a new recovery code")
+	}
+
+	if used, _, _, err := m.Recover("test", code, "again"); err != nil || used != nil {
+		t.Errorf("Recover with a used code: got: %v, %v, want: nil, nil", used, err)
+	}
+	if loggedIn, _, _, err := m.Login("test", "changed"); err != nil || loggedIn == nil {
+		t.Errorf("Login with the recovered password: %v", err)
+	}
+	if recovered, _, _, err := m.Recover("test", next, "again"); err != nil || recovered == nil {
+		t.Errorf("Recover with the new code: %v", err)
+	}
+}